@@ -7,6 +7,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/JojiiOfficial/DataManagerServer/handlers"
+	"github.com/JojiiOfficial/DataManagerServer/models"
 	"github.com/JojiiOfficial/DataManagerServer/services"
 	"github.com/jinzhu/gorm"
 
@@ -31,8 +33,22 @@ func startAPI() {
 	//Start loop to tick the services
 	go (func() {
 		for {
-			time.Sleep(time.Hour)
-
+			time.Sleep(time.Minute)
+
+			//Delete expired files
+			n, err := models.DeleteExpiredFiles(db, config)
+			if !LogError(err) && n > 0 {
+				log.Infof("Deleted %d expired files", n)
+			}
+
+			//Delete expired collections
+			c, err := models.DeleteExpiredCollections(db)
+			if !LogError(err) && c > 0 {
+				log.Infof("Deleted %d expired collections", c)
+			}
+
+			//Refresh mirrored files in background, downloads can take long
+			go handlers.RefreshMirrors(config, db)
 		}
 	})()
 
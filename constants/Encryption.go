@@ -1,49 +1,106 @@
 package constants
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 )
 
-// EncryptionCiphers supported encryption chipers
-var EncryptionCiphers = []string{
-	"aes",
+// Cipher a cipher for client side encryption
+type Cipher struct {
+	// Stable identifier stored for each file. Never change it
+	Name string
+
+	// Parameters a client needs to decrypt a file
+	Required []string
+	Optional []string
+}
+
+// MaxCipherParamLength max length of a cipher parameter value
+const MaxCipherParamLength = 1024
+
+// Parameters of key derivation functions for password based keys
+var kdfParams = []string{"kdf", "salt", "iterations", "memory", "threads"}
+
+// EncryptionCiphers supported encryption chipers. The order doesn't matter
+var EncryptionCiphers = []Cipher{
+	// Legacy cipher of old clients
+	{Name: "aes"},
+	{Name: "aes-256-gcm", Required: []string{"nonce"}, Optional: kdfParams},
+	{Name: "chacha20-poly1305", Required: []string{"nonce"}, Optional: kdfParams},
+	// age stores everything required in its header
+	{Name: "age", Optional: []string{"recipient"}},
+}
+
+// LegacyCiphers ciphers stored by their position in older versions. Only used for migrating them
+var LegacyCiphers = map[int32]string{
+	1: "aes",
 }
 
-// ChiperToInt cipter to int
-func ChiperToInt(c string) int32 {
+// Errors of cipher parameters
+var (
+	ErrMissingCipherParam = errors.New("missing cipher parameter")
+	ErrUnknownCipherParam = errors.New("unknown cipher parameter")
+	ErrCipherParamLength  = errors.New("cipher parameter too long")
+)
+
+// GetCipher returns the cipher with the given name
+func GetCipher(c string) *Cipher {
 	c = strings.ToLower(c)
-	for i, ec := range EncryptionCiphers {
-		if c == strings.ToLower(ec) {
-			return int32(i) + 1
+	for i := range EncryptionCiphers {
+		if EncryptionCiphers[i].Name == c {
+			return &EncryptionCiphers[i]
 		}
 	}
 
-	return 0
+	return nil
+}
+
+// IsValidCipher return true if given cipher is valid
+func IsValidCipher(c string) bool {
+	return GetCipher(c) != nil
 }
 
-// EncryptionIValid return true if encryption i is valid
-func EncryptionIValid(i int32) bool {
-	if i-1 < 0 || i-1 >= int32(len(EncryptionCiphers)) {
-		return false
+// CipherNames returns the names of all supported ciphers
+func CipherNames() []string {
+	names := make([]string, len(EncryptionCiphers))
+	for i := range EncryptionCiphers {
+		names[i] = EncryptionCiphers[i].Name
 	}
 
-	return true
+	return names
 }
 
-// ChiperToString cipter to int
-func ChiperToString(i int32) string {
-	if !EncryptionIValid(i) {
-		return ""
+// CheckParams returns an error if params can't be used for the cipher
+func (cipher Cipher) CheckParams(params map[string]string) error {
+	for _, param := range cipher.Required {
+		if len(params[param]) == 0 {
+			return fmt.Errorf("%w %s", ErrMissingCipherParam, param)
+		}
 	}
 
-	return EncryptionCiphers[i-1]
+	for param, value := range params {
+		if !cipher.hasParam(param) {
+			return fmt.Errorf("%w %s", ErrUnknownCipherParam, param)
+		}
+
+		if len(value) > MaxCipherParamLength {
+			return ErrCipherParamLength
+		}
+	}
+
+	return nil
 }
 
-// IsValidCipher return true if given cipher is valid
-func IsValidCipher(c string) bool {
-	c = strings.ToLower(c)
-	for _, ec := range EncryptionCiphers {
-		if strings.ToLower(ec) == c {
+func (cipher Cipher) hasParam(param string) bool {
+	for _, p := range cipher.Required {
+		if p == param {
+			return true
+		}
+	}
+
+	for _, p := range cipher.Optional {
+		if p == param {
 			return true
 		}
 	}
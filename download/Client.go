@@ -0,0 +1,207 @@
+package download
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	log "github.com/sirupsen/logrus"
+)
+
+//Errors returned by the client if a download isn't allowed
+var (
+	ErrHostNotAllowed    = errors.New("host not allowed")
+	ErrAddressNotAllowed = errors.New("address not allowed")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+)
+
+//blockedNetworks private, loopback, link-local and other non public networks
+var blockedNetworks = parseNetworks([]string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}, true)
+
+//policy decides which hosts and addresses can be downloaded from
+type policy struct {
+	allowPrivate    bool
+	allowedHosts    []string
+	deniedHosts     []string
+	allowedNetworks []*net.IPNet
+	deniedNetworks  []*net.IPNet
+}
+
+//NewClient creates a http client for downloading user supplied urls.
+//Timeouts of role are used if set, otherwise the ones of the config
+func NewClient(config *models.Config, role *models.Role) *http.Client {
+	conf := config.Server.URLDownloads
+	p := newPolicy(config)
+
+	connectTimeout, timeout := conf.ConnectTimeout, conf.Timeout
+	if role != nil && role.URLConnectTimeout > 0 {
+		connectTimeout = role.URLConnectTimeout
+	}
+	if role != nil && role.URLTimeout > 0 {
+		timeout = role.URLTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout: time.Duration(connectTimeout) * time.Second,
+		// Check the resolved address. Checking the hostname only
+		// would allow DNS records pointing to internal addresses
+		Control: p.control,
+	}
+
+	return &http.Client{
+		Timeout: time.Duration(timeout) * time.Second,
+		Transport: &http.Transport{
+			// No proxy, it would bypass the address check
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: time.Duration(connectTimeout) * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > conf.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+
+			// Redirect targets have to be allowed too
+			return p.checkURL(req.URL)
+		},
+	}
+}
+
+//CheckURL returns an error if the host of u is not allowed to be downloaded from.
+//The addresses are checked by the client while connecting
+func CheckURL(config *models.Config, u *url.URL) error {
+	return newPolicy(config).checkURL(u)
+}
+
+func newPolicy(config *models.Config) *policy {
+	conf := config.Server.URLDownloads
+	p := &policy{
+		allowPrivate:    conf.AllowPrivateNetworks,
+		allowedNetworks: parseNetworks(conf.AllowedNetworks, false),
+	}
+
+	for _, host := range conf.AllowedHosts {
+		p.allowedHosts = append(p.allowedHosts, strings.ToLower(host))
+	}
+
+	// Denied hosts can be hostnames or networks
+	for _, host := range conf.DeniedHosts {
+		if strings.Contains(host, "/") {
+			p.deniedNetworks = append(p.deniedNetworks, parseNetworks([]string{host}, false)...)
+		} else {
+			p.deniedHosts = append(p.deniedHosts, strings.ToLower(host))
+		}
+	}
+
+	return p
+}
+
+//Check the hostname of an url
+func (p *policy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrHostNotAllowed
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if matchesHost(host, p.deniedHosts) {
+		return ErrHostNotAllowed
+	}
+
+	if len(p.allowedHosts) > 0 && !matchesHost(host, p.allowedHosts) {
+		return ErrHostNotAllowed
+	}
+
+	return nil
+}
+
+//Check an address before connecting to it
+func (p *policy) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrAddressNotAllowed
+	}
+
+	if containsIP(p.deniedNetworks, ip) {
+		return ErrAddressNotAllowed
+	}
+
+	if !p.allowPrivate && containsIP(blockedNetworks, ip) && !containsIP(p.allowedNetworks, ip) {
+		return ErrAddressNotAllowed
+	}
+
+	return nil
+}
+
+//Return true if host is in hosts. Entries starting with "*." match all subdomains
+func matchesHost(host string, hosts []string) bool {
+	for _, entry := range hosts {
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		} else if host == entry {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Parse CIDR networks. Invalid networks are logged and skipped
+func parseNetworks(cidrs []string, mustParse bool) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			if mustParse {
+				panic(err)
+			}
+
+			log.Warnf("Invalid network '%s': %s", cidr, err)
+			continue
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
@@ -0,0 +1,326 @@
+package filestore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+//Encrypted files are split into chunks which are encrypted separately.
+//This allows reading any range without decrypting the whole file.
+//
+//Format: header | chunk 0 | chunk 1 | ... | last chunk
+//header: magic (4) | cipher (1) | chunk size (4) | nonce prefix (8)
+//chunk:  sealed content of chunk size bytes (less for the last one)
+//The nonce of a chunk is the prefix followed by the chunk index. The last
+//chunk is authenticated as last one, so truncated files are detected
+
+//Errors of encrypted files
+var (
+	ErrInvalidBlob     = errors.New("invalid encrypted file")
+	ErrUnknownCipher   = errors.New("unknown cipher")
+	ErrDecryptionError = errors.New("can't decrypt file")
+)
+
+//Ciphers for encrypting files at rest
+const (
+	AESGCMCipher           = "aes-256-gcm"
+	ChaCha20Poly1305Cipher = "chacha20-poly1305"
+)
+
+//IDs of the ciphers stored in the header. Never change them
+var cipherIDs = map[string]byte{
+	AESGCMCipher:           1,
+	ChaCha20Poly1305Cipher: 2,
+}
+
+var blobMagic = []byte("DMB1")
+
+const (
+	headerSize  = 17
+	prefixSize  = 8
+	chunkSize   = 64 * 1024
+	maxChunk    = 16 * 1024 * 1024
+	overhead    = 16
+	lastChunk   = 1
+	middleChunk = 0
+)
+
+//IsValidCipher return true if cipher can be used to encrypt files at rest
+func IsValidCipher(cipher string) bool {
+	_, ok := cipherIDs[cipher]
+	return ok
+}
+
+//Create the AEAD of a cipher
+func newAEAD(cipherID byte, key []byte) (cipher.AEAD, error) {
+	switch cipherID {
+	case cipherIDs[AESGCMCipher]:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case cipherIDs[ChaCha20Poly1305Cipher]:
+		return chacha20poly1305.New(key)
+	}
+
+	return nil, ErrUnknownCipher
+}
+
+//Build the nonce of a chunk
+func chunkNonce(prefix []byte, index uint32) []byte {
+	nonce := make([]byte, prefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], index)
+	return nonce
+}
+
+//Additional data of a chunk binds it to the header and marks the last chunk
+func chunkData(header []byte, flag byte) []byte {
+	return append(append([]byte{}, header...), flag)
+}
+
+//chunkWriter encrypts all data written to it
+type chunkWriter struct {
+	f      *os.File
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint32
+	buf    []byte
+}
+
+//Create a writer encrypting into f using the cipher and key
+func newChunkWriter(f *os.File, cipherName string, key []byte) (*chunkWriter, error) {
+	cipherID, ok := cipherIDs[cipherName]
+	if !ok {
+		return nil, ErrUnknownCipher
+	}
+
+	aead, err := newAEAD(cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, blobMagic)
+	header[4] = cipherID
+	binary.BigEndian.PutUint32(header[5:9], chunkSize)
+	if _, err = io.ReadFull(rand.Reader, header[9:]); err != nil {
+		return nil, err
+	}
+
+	if _, err = f.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &chunkWriter{
+		f:      f,
+		aead:   aead,
+		header: header,
+		prefix: header[9:],
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (writer *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only written if more data follows,
+		// since the last chunk has to be marked
+		if len(writer.buf) == chunkSize {
+			if err := writer.seal(middleChunk); err != nil {
+				return n - len(p), err
+			}
+		}
+
+		c := copy(writer.buf[len(writer.buf):chunkSize], p)
+		writer.buf = writer.buf[:len(writer.buf)+c]
+		p = p[c:]
+	}
+
+	return n, nil
+}
+
+//Encrypt and write the buffered chunk
+func (writer *chunkWriter) seal(flag byte) error {
+	sealed := writer.aead.Seal(nil, chunkNonce(writer.prefix, writer.index), writer.buf, chunkData(writer.header, flag))
+	if _, err := writer.f.Write(sealed); err != nil {
+		return err
+	}
+
+	writer.index++
+	writer.buf = writer.buf[:0]
+	return nil
+}
+
+//Close writes the last chunk and closes the file
+func (writer *chunkWriter) Close() error {
+	if err := writer.seal(lastChunk); err != nil {
+		writer.f.Close()
+		return err
+	}
+
+	return writer.f.Close()
+}
+
+//chunkReader decrypts an encrypted file
+type chunkReader struct {
+	f         *os.File
+	aead      cipher.AEAD
+	header    []byte
+	prefix    []byte
+	chunkSize int64
+	chunks    int64
+	size      int64
+	pos       int64
+
+	// Last decrypted chunk
+	cached      []byte
+	cachedIndex int64
+}
+
+//Create a reader decrypting f using key
+func newChunkReader(f *os.File, key []byte) (*chunkReader, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err = io.ReadFull(f, header); err != nil || !bytes.Equal(header[:4], blobMagic) {
+		return nil, ErrInvalidBlob
+	}
+
+	aead, err := newAEAD(header[4], key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[5:9])
+	if size == 0 || size > maxChunk {
+		return nil, ErrInvalidBlob
+	}
+
+	// The file contains at least the last chunk
+	encSize := stat.Size() - headerSize
+	if encSize < overhead {
+		return nil, ErrInvalidBlob
+	}
+
+	sealedSize := int64(size) + overhead
+	chunks := (encSize + sealedSize - 1) / sealedSize
+	lastSize := encSize - (chunks-1)*sealedSize
+	if lastSize < overhead {
+		return nil, ErrInvalidBlob
+	}
+
+	return &chunkReader{
+		f:           f,
+		aead:        aead,
+		header:      header,
+		prefix:      header[9:],
+		chunkSize:   int64(size),
+		chunks:      chunks,
+		size:        (chunks-1)*int64(size) + lastSize - overhead,
+		cachedIndex: -1,
+	}, nil
+}
+
+//Size returns the size of the decrypted content
+func (reader *chunkReader) Size() int64 {
+	return reader.size
+}
+
+//Decrypt a chunk
+func (reader *chunkReader) chunk(index int64) ([]byte, error) {
+	if index == reader.cachedIndex {
+		return reader.cached, nil
+	}
+
+	sealedSize := reader.chunkSize + overhead
+	sealed := make([]byte, sealedSize)
+	n, err := reader.f.ReadAt(sealed, headerSize+index*sealedSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	flag := byte(middleChunk)
+	if index == reader.chunks-1 {
+		flag = lastChunk
+	}
+
+	plain, err := reader.aead.Open(sealed[:0], chunkNonce(reader.prefix, uint32(index)), sealed[:n], chunkData(reader.header, flag))
+	if err != nil {
+		return nil, ErrDecryptionError
+	}
+
+	reader.cached, reader.cachedIndex = plain, index
+	return plain, nil
+}
+
+func (reader *chunkReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	var n int
+	for n < len(p) {
+		if off >= reader.size {
+			return n, io.EOF
+		}
+
+		plain, err := reader.chunk(off / reader.chunkSize)
+		if err != nil {
+			return n, err
+		}
+
+		c := copy(p[n:], plain[off%reader.chunkSize:])
+		n += c
+		off += int64(c)
+	}
+
+	return n, nil
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadAt(p, reader.pos)
+	reader.pos += int64(n)
+
+	// Partial reads are fine
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (reader *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += reader.pos
+	case io.SeekEnd:
+		offset += reader.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	reader.pos = offset
+	return offset, nil
+}
+
+func (reader *chunkReader) Close() error {
+	return reader.f.Close()
+}
@@ -0,0 +1,145 @@
+package filestore
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+//Blob the content of a stored file
+type Blob interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+
+	// Size of the (decrypted) content
+	Size() int64
+}
+
+//Unencrypted file
+type plainBlob struct {
+	*os.File
+	size int64
+}
+
+func (blob plainBlob) Size() int64 {
+	return blob.size
+}
+
+//Open opens the content of a file. Files encrypted at rest are decrypted transparently
+func Open(config *models.Config, file *models.File) (Blob, error) {
+	return OpenPath(file, config.GetStorageFile(file.LocalName))
+}
+
+//OpenPath opens a file belonging to file, eg. a thumbnail. It is decrypted using the key of file
+func OpenPath(file *models.File, path string) (Blob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsEncrypted(file) {
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return plainBlob{File: f, size: stat.Size()}, nil
+	}
+
+	dataKey, err := getKeyring().unwrap(file.DataKey, file.DataKeyID)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	reader, err := newChunkReader(f, dataKey)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+//Create creates the local file for new content of file. If encryption at rest is
+//enabled, a new data key is assigned to file, so it has to be saved afterwards
+func Create(config *models.Config, file *models.File) (io.WriteCloser, error) {
+	file.DataKey = nil
+	file.DataKeyID = ""
+
+	var dataKey []byte
+	if config.Server.EncryptionAtRest.Enabled {
+		var err error
+		if dataKey, err = newDataKey(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return create(config.GetStorageFile(file.LocalName), config.Server.EncryptionAtRest.Cipher, dataKey)
+}
+
+//CreatePath creates a file belonging to file, eg. a thumbnail. It is encrypted if file is encrypted
+func CreatePath(config *models.Config, file *models.File, path string) (io.WriteCloser, error) {
+	if !IsEncrypted(file) {
+		return create(path, "", nil)
+	}
+
+	dataKey, err := getKeyring().unwrap(file.DataKey, file.DataKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return create(path, config.Server.EncryptionAtRest.Cipher, dataKey)
+}
+
+//Create a file. Data is encrypted if dataKey is set
+func create(path, cipher string, dataKey []byte) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if dataKey == nil {
+		return f, nil
+	}
+
+	// Encryption at rest may be disabled by now. Keep encrypting files having a key
+	if !IsValidCipher(cipher) {
+		cipher = AESGCMCipher
+	}
+
+	writer, err := newChunkWriter(f, cipher, dataKey)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+//IsEncrypted return true if the file is encrypted at rest
+func IsEncrypted(file *models.File) bool {
+	return len(file.DataKey) > 0
+}
+
+//DetectMime detects the mime type of the content of file
+func DetectMime(config *models.Config, file *models.File) (string, error) {
+	blob, err := Open(config, file)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	mime, err := mimetype.DetectReader(blob)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Split(mime.String(), ";")[0], nil
+}
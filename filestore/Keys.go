@@ -0,0 +1,290 @@
+package filestore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/jinzhu/gorm"
+)
+
+//Errors of master keys
+var (
+	ErrNoMasterKey      = errors.New("no master key set")
+	ErrInvalidMasterKey = errors.New("master key must be 32 bytes or their base64 encoding")
+	ErrUnknownMasterKey = errors.New("file key is wrapped by an unknown master key")
+)
+
+const keySize = 32
+
+//masterKey a key wrapping the data keys of files
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+//keyring all usable master keys. New data keys are wrapped using current
+type keyring struct {
+	current *masterKey
+	keys    map[string]*masterKey
+}
+
+//Keys loaded by LoadKeys
+var (
+	loadedKeys *keyring
+	keysMutex  sync.RWMutex
+)
+
+//LoadKeys loads the master keys of the config. Files can't be encrypted or
+//decrypted before. The current key is required if encryption at rest is enabled
+func LoadKeys(config *models.Config) error {
+	conf := config.Server.EncryptionAtRest
+	if conf.Enabled && !IsValidCipher(conf.Cipher) {
+		return ErrUnknownCipher
+	}
+
+	ring := &keyring{
+		keys: make(map[string]*masterKey),
+	}
+
+	// Current key
+	key, err := readKey(conf.MasterKey, conf.MasterKeyFile)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		ring.current = key
+		ring.keys[key.id] = key
+	} else if conf.Enabled {
+		return ErrNoMasterKey
+	}
+
+	// Old keys are only used to decrypt files not rotated yet
+	for _, file := range conf.OldKeyFiles {
+		key, err := readKey("", file)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			ring.keys[key.id] = key
+		}
+	}
+
+	keysMutex.Lock()
+	loadedKeys = ring
+	keysMutex.Unlock()
+	return nil
+}
+
+//Read a master key from a base64 string or a file. Returns nil if both are empty
+func readKey(encoded, file string) (*masterKey, error) {
+	raw := []byte(encoded)
+	if len(file) > 0 {
+		var err error
+		if raw, err = ioutil.ReadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	// Key files can contain the raw key
+	key := raw
+	if len(key) != keySize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil {
+			return nil, ErrInvalidMasterKey
+		}
+		key = decoded
+	}
+
+	if len(key) != keySize {
+		return nil, ErrInvalidMasterKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(key)
+	return &masterKey{
+		id:   hex.EncodeToString(hash[:8]),
+		aead: aead,
+	}, nil
+}
+
+//Get the loaded keys
+func getKeyring() *keyring {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+	return loadedKeys
+}
+
+//Wrap a data key using the current master key. Returns the wrapped key and the ID of the master key
+func (ring *keyring) wrap(dataKey []byte) ([]byte, string, error) {
+	if ring == nil || ring.current == nil {
+		return nil, "", ErrNoMasterKey
+	}
+
+	nonce := make([]byte, ring.current.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, "", err
+	}
+
+	return ring.current.aead.Seal(nonce, nonce, dataKey, []byte(ring.current.id)), ring.current.id, nil
+}
+
+//Unwrap a data key wrapped by the master key with keyID
+func (ring *keyring) unwrap(wrapped []byte, keyID string) ([]byte, error) {
+	if ring == nil {
+		return nil, ErrNoMasterKey
+	}
+
+	key, ok := ring.keys[keyID]
+	if !ok {
+		return nil, ErrUnknownMasterKey
+	}
+
+	nonceSize := key.aead.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, ErrDecryptionError
+	}
+
+	dataKey, err := key.aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(keyID))
+	if err != nil {
+		return nil, ErrDecryptionError
+	}
+
+	return dataKey, nil
+}
+
+//Generate a new data key for the file and return it unwrapped
+func newDataKey(file *models.File) ([]byte, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped, keyID, err := getKeyring().wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file.DataKey = wrapped
+	file.DataKeyID = keyID
+	return dataKey, nil
+}
+
+//RotateKeys wraps the data keys of all files, including quarantined ones, with
+//the current master key. Returns the count of rewrapped keys
+func RotateKeys(config *models.Config, db *gorm.DB) (int, error) {
+	ring := getKeyring()
+	if ring == nil || ring.current == nil {
+		return 0, ErrNoMasterKey
+	}
+
+	var files []models.File
+	err := db.Select("id, data_key, data_key_id").
+		Where("data_key IS NOT NULL AND data_key_id <> ?", ring.current.id).
+		Find(&files).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, file := range files {
+			dataKey, err := ring.unwrap(file.DataKey, file.DataKeyID)
+			if err != nil {
+				return err
+			}
+
+			wrapped, keyID, err := ring.wrap(dataKey)
+			if err != nil {
+				return err
+			}
+
+			err = tx.Model(&file).UpdateColumns(map[string]interface{}{
+				"data_key":    wrapped,
+				"data_key_id": keyID,
+			}).Error
+			if err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Quarantined files keep their key in their info
+	quarantined, err := rotateQuarantineKeys(config, ring)
+	if err != nil {
+		return count, err
+	}
+
+	return count + quarantined, nil
+}
+
+//Rewrap the data keys stored in quarantine infos. Returns the count of
+//rewrapped keys of files which were never stored in the database
+func rotateQuarantineKeys(config *models.Config, ring *keyring) (int, error) {
+	infoFiles, err := filepath.Glob(config.GetQuarantineFile("*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, infoFile := range infoFiles {
+		info, err := models.ReadQuarantineInfo(infoFile)
+		if err != nil {
+			return count, err
+		}
+
+		if len(info.DataKey) == 0 || info.DataKeyID == ring.current.id {
+			continue
+		}
+
+		dataKey, err := ring.unwrap(info.DataKey, info.DataKeyID)
+		if err != nil {
+			return count, err
+		}
+
+		if info.DataKey, info.DataKeyID, err = ring.wrap(dataKey); err != nil {
+			return count, err
+		}
+
+		if err = info.Write(infoFile); err != nil {
+			return count, err
+		}
+
+		// Files with an ID were counted already
+		if info.FileID == 0 {
+			count++
+		}
+	}
+
+	return count, nil
+}
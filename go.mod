@@ -6,6 +6,7 @@ require (
 	github.com/JojiiOfficial/configService v0.0.0-20200219132202-6e71512e2e28
 	github.com/JojiiOfficial/gaw v1.1.56
 	github.com/JojiiOfficial/shred v1.0.1
+	github.com/alecthomas/chroma v0.8.2
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
 	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d // indirect
 	github.com/fatih/color v1.9.0
@@ -13,7 +14,11 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/h2non/filetype v1.0.12
 	github.com/jinzhu/gorm v1.9.12
+	github.com/mattn/go-sqlite3 v2.0.1+incompatible
 	github.com/sbani/go-humanizer v0.3.1
 	github.com/sirupsen/logrus v1.5.0
+	github.com/yuin/goldmark v1.2.1
+	golang.org/x/crypto v0.0.0-20191205180655-e7c4368fe9dd
+	golang.org/x/image v0.0.0-20200430140353-33d19683fad8
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 )
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/gaw"
+	"github.com/gorilla/mux"
+)
+
+//CollectionHandler handler for publishing collections (create/delete/list)
+func CollectionHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	if !gaw.IsInStringArray(action, []string{"create", "delete", "list"}) {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	var request models.CollectionRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	switch action {
+	case "list":
+		collections, err := models.FindUserCollections(handlerData.Db, handlerData.User)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+
+		response := models.CollectionListResponse{
+			Collections: make([]models.CollectionResponseItem, len(collections)),
+		}
+		for i := range collections {
+			response.Collections[i] = collections[i].ToResponse()
+		}
+
+		sendResponse(w, models.ResponseSuccess, "", response)
+	case "delete":
+		collection, found, err := models.GetPublicCollection(handlerData.Db, request.PublicName)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+		if !found || collection.UserID != handlerData.User.ID {
+			sendResponse(w, models.ResponseError, "Collection not found", nil, http.StatusNotFound)
+			return
+		}
+
+		if LogError(collection.Delete(handlerData.Db)) {
+			sendServerError(w)
+			return
+		}
+
+		sendResponse(w, models.ResponseSuccess, "", nil)
+	case "create":
+		collectionType, err := models.ParseCollectionType(request.Type)
+		if err != nil {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Groups and tags need a name
+		if collectionType != models.NamespaceCollection && len(request.Name) == 0 {
+			sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+			return
+		}
+
+		// Select namespace
+		namespace := models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+
+		// Handle namespace errors (not found || no access)
+		if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+			return
+		}
+
+		expiry, err := models.ParseExpiry(request.Expiry)
+		if err != nil {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+
+		collection := &models.Collection{
+			Type:        collectionType,
+			NamespaceID: namespace.ID,
+			Namespace:   namespace,
+			UserID:      handlerData.User.ID,
+		}
+
+		if collectionType == models.TagCollection {
+			collection.Name = models.NormalizeTagName(request.Name)
+		} else if collectionType == models.GroupCollection {
+			collection.Name = request.Name
+		}
+
+		if expiry > 0 {
+			expiresAt := time.Now().Add(expiry)
+			collection.ExpiresAt = &expiresAt
+		}
+
+		if LogError(collection.SetPassword(request.Password)) {
+			sendServerError(w)
+			return
+		}
+
+		nameTaken, err := collection.Publish(handlerData.Db, request.PublicName)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+		if nameTaken {
+			sendResponse(w, models.ResponseError, "public name already exists", nil, http.StatusConflict)
+			return
+		}
+
+		sendResponse(w, models.ResponseSuccess, "", collection.ToResponse())
+	}
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/DataManagerServer/search"
+	"github.com/JojiiOfficial/DataManagerServer/thumbnail"
+	log "github.com/sirupsen/logrus"
+)
+
+//ExportNamespaceHandler streams the files of a namespace as archive
+func ExportNamespaceHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	var request models.ExportRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	format, err := web.ParseArchiveFormat(request.Format)
+	if err != nil {
+		sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Select namespace
+	namespace := models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+
+	// Handle namespace errors (not found || no access)
+	if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+		return
+	}
+
+	// Find files
+	loaded := handlerData.Db.Model(&models.File{}).
+		Where("namespace_id = ?", namespace.ID).
+		Preload("Tags").
+		Preload("Groups").
+		Preload("Meta")
+
+	if len(request.Name) > 0 {
+		loaded = loaded.Where("name LIKE ?", "%"+request.Name+"%")
+	}
+
+	var files []models.File
+	if err = loaded.Find(&files).Error; LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	// Build manifest
+	manifest := models.ExportManifest{
+		Version:   models.ExportManifestVersion,
+		Namespace: namespace.Name,
+		Created:   time.Now(),
+	}
+
+	var exportFiles []models.File
+	names := web.ArchiveNames{
+		models.ExportManifestName: true,
+	}
+
+	for _, file := range files {
+		// Filter tags and groups
+		if (len(request.Tags) > 0 && !file.IsInTagList(request.Tags)) ||
+			(len(request.Groups) > 0 && !file.IsInGroupList(request.Groups)) {
+			continue
+		}
+
+		// Skip files missing in filestore
+		blob, err := filestore.Open(handlerData.Config, &file)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		file.FileSize = blob.Size()
+		blob.Close()
+
+		manifest.Files = append(manifest.Files, file.ToManifestFile("files/"+names.Get(file.Name)))
+		exportFiles = append(exportFiles, file)
+	}
+
+	manifestData, err := json.Marshal(manifest)
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	w.Header().Set(models.HeaderContentType, format.ContentType())
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+format.Filename(namespace.Name)+"\"")
+
+	archive, err := web.NewArchiveWriter(format, w)
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	// Write manifest first, so imports know the files before reading them
+	err = archive.WriteFile(models.ExportManifestName, int64(len(manifestData)), manifest.Created, bytes.NewReader(manifestData))
+	if LogError(err) {
+		return
+	}
+
+	for i, file := range exportFiles {
+		f, err := filestore.Open(handlerData.Config, &exportFiles[i])
+		if LogError(err) {
+			return
+		}
+
+		err = archive.WriteFile(manifest.Files[i].Path, file.FileSize, file.UpdatedAt, f)
+		f.Close()
+		if LogError(err) {
+			return
+		}
+	}
+
+	LogError(archive.Close())
+}
+
+//ImportNamespaceHandler imports an exported archive into a namespace
+func ImportNamespaceHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	// Check if user is allowed to upload files
+	if !handlerData.User.CanUploadFiles() {
+		sendResponse(w, models.ResponseError, "not allowed to upload files", nil, http.StatusForbidden)
+		return
+	}
+
+	// Select namespace
+	namespace := models.FindNamespace(handlerData.Db, r.URL.Query().Get("ns"), handlerData.User)
+
+	// Handle namespace errors (not found || no access)
+	if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+		return
+	}
+
+	body := io.LimitReader(r.Body, handlerData.Config.Webserver.MaxUploadFileLength)
+	archive, err := web.NewArchiveReader(body, handlerData.Config.Server.PathConfig.FileStore)
+	if err != nil {
+		sendResponse(w, models.ResponseError, "invalid archive", nil, http.StatusUnprocessableEntity)
+		return
+	}
+	defer archive.Close()
+
+	// Read manifest
+	name, reader, err := archive.Next()
+	if err != nil || name != models.ExportManifestName {
+		sendResponse(w, models.ResponseError, "archive has no manifest", nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	var manifest models.ExportManifest
+	manifestData, err := ioutil.ReadAll(io.LimitReader(reader, handlerData.Config.Webserver.MaxUploadFileLength))
+	if err != nil || json.Unmarshal(manifestData, &manifest) != nil {
+		sendResponse(w, models.ResponseError, "invalid manifest", nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if manifest.Version > models.ExportManifestVersion {
+		sendResponse(w, models.ResponseError, "unsupported manifest version", nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	manifestFiles := make(map[string]models.ExportManifestFile)
+	for _, item := range manifest.Files {
+		manifestFiles[item.Path] = item
+	}
+
+	var response models.ImportResponse
+
+	for {
+		name, reader, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			sendResponse(w, models.ResponseError, "invalid archive", response, http.StatusUnprocessableEntity)
+			return
+		}
+
+		// Ignore files not listed in manifest
+		item, ok := manifestFiles[name]
+		if !ok {
+			log.Warn("Ignoring unknown archive entry ", name)
+			continue
+		}
+
+		file, status, msg := importArchiveFile(handlerData, namespace, item, reader)
+		if file == nil {
+			sendResponse(w, models.ResponseError, msg, response, status)
+			return
+		}
+
+		response.Files = append(response.Files, models.UploadResponse{
+			FileID:         file.ID,
+			Filename:       file.Name,
+			PublicFilename: file.PublicFilename.String,
+		})
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", response)
+}
+
+//Import a single file of an archive. Returns nil, the statuscode and a message on error
+func importArchiveFile(handlerData web.HandlerData, namespace *models.Namespace, item models.ExportManifestFile, reader io.Reader) (*models.File, int, string) {
+	// Check namespace quota
+	if ok, err := namespace.CheckQuota(handlerData.Db, item.Size, 1); LogError(err) {
+		return nil, http.StatusInternalServerError, models.ServerError
+	} else if !ok {
+		return nil, http.StatusInsufficientStorage, "namespace quota exceeded"
+	}
+
+	file := &models.File{
+		Name:        item.Name,
+		Namespace:   namespace,
+		NamespaceID: namespace.ID,
+		ExpiresAt:   item.Expiry,
+		Description: item.Description,
+	}
+	file.CreatedAt = item.Created
+	file.UpdatedAt = item.Updated
+	file.SetEncryption(item.Encryption, item.EncParams)
+
+	if !file.SetUniqueFilename(handlerData.Db) {
+		return nil, http.StatusInternalServerError, models.ServerError
+	}
+
+	// Write content
+	f, err := filestore.Create(handlerData.Config, file)
+	if LogError(err) {
+		return nil, http.StatusInternalServerError, models.ServerError
+	}
+
+	size, err := io.Copy(f, reader)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if LogError(err) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusUnprocessableEntity, "invalid archive"
+	}
+	file.FileSize = size
+
+	// Check quota again. The size in the manifest isn't trusted
+	if ok, err := namespace.CheckQuota(handlerData.Db, size, 1); LogError(err) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInternalServerError, models.ServerError
+	} else if !ok {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInsufficientStorage, "namespace quota exceeded"
+	}
+
+	// Check role upload limit
+	if maxSize := handlerData.User.Role.MaxUploadFileSize; maxSize > 0 && size > maxSize {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusRequestEntityTooLarge, item.Name + " is too large"
+	}
+
+	// Scan for malware
+	if status, message := scanUpload(handlerData, file); status != 0 {
+		return nil, status, item.Name + ": " + message
+	}
+
+	// Detect mime type
+	mime, err := filestore.DetectMime(handlerData.Config, file)
+	if err != nil {
+		log.Info("Can't detect mime: ", err.Error())
+	} else {
+		file.FileType = mime
+	}
+
+	if !file.CanUpload(handlerData.User) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusUnsupportedMediaType, "filetype of " + item.Name + " not allowed"
+	}
+
+	// Set tags and groups
+	for _, tag := range item.Tags {
+		file.Tags = append(file.Tags, *models.GetTag(handlerData.Db, tag, namespace, handlerData.User))
+	}
+	for _, group := range item.Groups {
+		file.Groups = append(file.Groups, *models.GetGroup(handlerData.Db, group, namespace, handlerData.User))
+	}
+
+	// Keep public name if it's still available
+	if len(item.PublicName) > 0 {
+		if _, found, _ := models.GetPublicFile(handlerData.Db, item.PublicName); !found {
+			file.PublicFilename = sql.NullString{
+				String: item.PublicName,
+				Valid:  true,
+			}
+			file.IsPublic = item.IsPublic && file.CanPublish(handlerData.User)
+		}
+	}
+
+	if LogError(file.Insert(handlerData.Db, handlerData.User)) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInternalServerError, models.ServerError
+	}
+
+	// Set metadata
+	if err = file.SetMeta(handlerData.Db, item.Meta); err != nil {
+		LogError(file.Delete(handlerData.Db, handlerData.Config))
+		return nil, http.StatusUnprocessableEntity, err.Error()
+	}
+
+	// Index content for full-text search and create thumbnails
+	go (func(file models.File) {
+		LogError(search.IndexFile(handlerData.Db, handlerData.Config, file))
+		LogError(thumbnail.Generate(handlerData.Db, handlerData.Config, file))
+	})(*file)
+
+	return file, 0, ""
+}
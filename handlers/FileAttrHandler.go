@@ -7,6 +7,7 @@ import (
 	"github.com/JojiiOfficial/DataManagerServer/models"
 	"github.com/JojiiOfficial/gaw"
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
 )
 
 //AttributeHandler handler for attributes
@@ -18,7 +19,7 @@ func AttributeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 	action, hasAction := vars["action"]
 
 	//validate action and attribute kind
-	if !hasAttribute || !hasAction || !gaw.IsInStringArray(action, []string{"update", "delete"}) || !gaw.IsInStringArray(attributeKind, []string{"tag", "group"}) {
+	if !hasAttribute || !hasAction || !gaw.IsInStringArray(action, []string{"update", "delete", "merge", "rename"}) || !gaw.IsInStringArray(attributeKind, []string{"tag", "group"}) {
 		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
 		return
 	}
@@ -43,11 +44,31 @@ func AttributeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 		return
 	}
 
-	if action == "update" && len(request.NewName) == 0 {
+	if action != "delete" && len(request.NewName) == 0 {
 		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
 		return
 	}
 
+	//Rename all matching attributes
+	if action == "rename" {
+		renamed, err := models.BulkRenameAttributes(handlerData.Db, models.AttributeType(attributeKind), request.Name, request.NewName, namespace.ID, handlerData.User.ID, request.Merge)
+		if handleRenameAttributeError(w, err) {
+			return
+		}
+
+		var response models.AttributeRenameResponse
+		for _, item := range renamed {
+			response.Renamed = append(response.Renamed, models.AttributeRenameItem{
+				Name:    item.Name,
+				NewName: item.NewName,
+				Merged:  item.Merged,
+			})
+		}
+
+		sendResponse(w, models.ResponseSuccess, "", response)
+		return
+	}
+
 	if attributeKind == "tag" {
 		//Find instance
 		tag, err := models.FindTag(handlerData.Db, request.Name, namespace, handlerData.User)
@@ -71,13 +92,10 @@ func AttributeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 				sendServerError(w)
 				return
 			}
-		} else if action == "update" {
-			//Update tags name
-			tag.Name = request.NewName
-			err := handlerData.Db.Save(tag).Error
-
-			if LogError(err) {
-				sendServerError(w)
+		} else {
+			//Update tags name. Merge into existing tag if requested
+			_, err := models.RenameAttribute(handlerData.Db, models.TagAttribute, tag.ID, request.NewName, namespace.ID, handlerData.User.ID, action == "merge" || request.Merge)
+			if handleRenameAttributeError(w, err) {
 				return
 			}
 		}
@@ -104,13 +122,10 @@ func AttributeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 				sendServerError(w)
 				return
 			}
-		} else if action == "update" {
-			//Update groups name
-			group.Name = request.NewName
-			err := handlerData.Db.Save(group).Error
-
-			if LogError(err) {
-				sendServerError(w)
+		} else {
+			//Update groups name. Merge into existing group if requested
+			_, err := models.RenameAttribute(handlerData.Db, models.GroupAttribute, group.ID, request.NewName, namespace.ID, handlerData.User.ID, action == "merge" || request.Merge)
+			if handleRenameAttributeError(w, err) {
 				return
 			}
 		}
@@ -118,3 +133,114 @@ func AttributeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 
 	sendResponse(w, models.ResponseSuccess, "", nil)
 }
+
+//Send a response for errors of renaming attributes. Returns true if an error was handled
+func handleRenameAttributeError(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if err == models.ErrAttributeExists {
+		sendResponse(w, models.ResponseError, "name already exists, use merge", nil, http.StatusConflict)
+		return true
+	}
+
+	if err == models.ErrInvalidRenamePattern || err == models.ErrTagIntoChild {
+		sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+		return true
+	}
+
+	LogError(err)
+	sendServerError(w)
+	return true
+}
+
+//ListAttributesHandler lists tags or groups with their usage
+func ListAttributesHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	attributeType := models.AttributeType(mux.Vars(r)["attribute"])
+	if !attributeType.IsValid() {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	var request models.AttributeListRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	filter, ok := attributeNamespaceFilter(handlerData, w, request)
+	if !ok {
+		return
+	}
+
+	if request.Limit > models.MaxListLimit {
+		request.Limit = models.MaxListLimit
+	}
+
+	usage, err := models.FindAttributeUsage(handlerData.Db, attributeType, request.Prefix, request.Limit, filter)
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	var response models.AttributeListResponse
+	for _, item := range usage {
+		response.Attributes = append(response.Attributes, models.AttributeResponseItem{
+			Name:      item.Name,
+			Namespace: item.Namespace,
+			Files:     item.Files,
+			Size:      item.Size,
+			LastUsed:  item.LastUsed,
+		})
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", response)
+}
+
+//TagTreeHandler returns the hierarchy of nested tags
+func TagTreeHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	var request models.AttributeListRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	filter, ok := attributeNamespaceFilter(handlerData, w, request)
+	if !ok {
+		return
+	}
+
+	usage, err := models.FindTagTreeUsage(handlerData.Db, request.Prefix, filter)
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", models.TagTreeResponse{
+		Tags: models.BuildTagTree(usage),
+	})
+}
+
+//Return a filter for the requested namespaces. Returns false if the namespace can't be used
+func attributeNamespaceFilter(handlerData web.HandlerData, w http.ResponseWriter, request models.AttributeListRequest) (func(*gorm.DB) *gorm.DB, bool) {
+	if !request.AllNamespaces {
+		//Find namespace
+		namespace := models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+
+		// Handle namespace errors (not found || no access)
+		if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+			return nil, false
+		}
+
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("namespaces.id = ?", namespace.ID)
+		}, true
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		//Use all accessible namespaces
+		if handlerData.User.CanReadForeignNamespace() {
+			return db
+		}
+		return db.Where("namespaces.creator = ?", handlerData.User.ID)
+	}, true
+}
@@ -4,18 +4,22 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/JojiiOfficial/DataManagerServer/constants"
+	"github.com/JojiiOfficial/DataManagerServer/download"
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
 	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
 	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/DataManagerServer/search"
+	"github.com/JojiiOfficial/DataManagerServer/thumbnail"
 	"github.com/JojiiOfficial/gaw"
-	"github.com/gabriel-vasile/mimetype"
 	"github.com/gorilla/mux"
 	"github.com/h2non/filetype"
 	"github.com/jinzhu/gorm"
@@ -48,9 +52,23 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		return
 	}
 
-	// Check requested encryption type
-	if len(request.Encryption) > 0 && !constants.IsValidCipher(request.Encryption) {
-		sendResponse(w, models.ResponseError, "Encryption not supported", nil, http.StatusUnprocessableEntity)
+	// Check requested encryption type and its parameters
+	if len(request.Encryption) > 0 {
+		cipher := constants.GetCipher(request.Encryption)
+		if cipher == nil {
+			sendResponse(w, models.ResponseError, "Encryption not supported", nil, http.StatusUnprocessableEntity)
+			return
+		}
+
+		if err = cipher.CheckParams(request.EncParams); err != nil {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// Check metadata
+	if err = models.ValidateMeta(request.Attributes.Meta); err != nil {
+		sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
 		return
 	}
 
@@ -77,6 +95,18 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 				sendResponse(w, models.ResponseError, "missing or malformed url", nil, http.StatusUnprocessableEntity)
 				return
 			}
+
+			// Check refresh interval of mirrors
+			if _, err = models.ParseRefreshInterval(request.Refresh); err != nil {
+				sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+				return
+			}
+
+			// Check if url can be downloaded from
+			if u, _ := url.Parse(request.URL); download.CheckURL(handlerData.Config, u) != nil {
+				sendResponse(w, models.ResponseError, "url not allowed", nil, http.StatusForbidden)
+				return
+			}
 		}
 	default:
 		{
@@ -86,9 +116,136 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		}
 	}
 
+	// Download URLs in background
+	if request.UploadType == models.URLUploadType {
+		job, err := models.NewJob(handlerData.Db, models.URLDownloadJobType, handlerData.User)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+
+		go downloadURLUpload(handlerData, request, job)
+
+		sendResponse(w, models.ResponseSuccess, "", job.ToResponse(), http.StatusAccepted)
+		return
+	}
+
+	file, status, message := storeUpload(handlerData, request, func(f io.Writer, file *models.File) (int, string) {
+		// Read from uploaded file
+		r.ParseMultipartForm(handlerData.User.Role.MaxUploadFileSize)
+
+		uploadfile, _, err := r.FormFile("uploadfile")
+		if err != nil {
+			fmt.Println(err)
+			return http.StatusBadRequest, "Bad request"
+		}
+		defer uploadfile.Close()
+
+		// Copy stream to file
+		size, err := io.Copy(f, uploadfile)
+		if LogError(err) {
+			return http.StatusInternalServerError, ""
+		}
+
+		// Set filesize to written bytes
+		file.FileSize = int64(size)
+		return 0, ""
+	})
+
+	if file == nil {
+		if status == http.StatusInternalServerError {
+			sendServerError(w)
+		} else {
+			sendResponse(w, models.ResponseError, message, nil, status)
+		}
+		return
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", models.UploadResponse{
+		FileID:         file.ID,
+		Filename:       file.Name,
+		PublicFilename: file.PublicFilename.String,
+	})
+}
+
+//Download the URL of an upload into a new file. The state is reported in job
+func downloadURLUpload(handlerData web.HandlerData, request models.UploadRequest, job *models.Job) {
+	ctx, release := job.WithCancel()
+	defer release()
+
+	refresh, _ := models.ParseRefreshInterval(request.Refresh)
+
+	file, status, message := storeUpload(handlerData, request, func(f io.Writer, file *models.File) (int, string) {
+		// Mirror the url if a refresh interval is set
+		if refresh > 0 {
+			file.SetMirror(request.URL, refresh)
+		} else if file.SourceURL != request.URL {
+			file.StopMirror()
+		}
+
+		// Read from HTTP request
+		status, err := downloadHTTP(ctx, handlerData.Config, handlerData.Db, job, handlerData.User, request.URL, f, file)
+		if err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+
+		// Content didn't change
+		if status == http.StatusNotModified {
+			return status, "not modified"
+		}
+
+		// Check statuscode
+		if status > 299 || status < 200 {
+			return http.StatusBadRequest, "Non ok response: " + strconv.Itoa(status)
+		}
+
+		return 0, ""
+	})
+
+	if file == nil {
+		// Canceled by user
+		if ctx.Err() != nil {
+			LogError(job.Cancel(handlerData.Db))
+			return
+		}
+
+		// Keep replaced file if the content didn't change
+		if status == http.StatusNotModified {
+			// Apply new refresh interval anyway
+			if refresh > 0 {
+				replaced, err := models.FindFile(handlerData.Db, request.ReplaceFile, handlerData.User.ID)
+				if !LogError(err) {
+					replaced.SetMirror(request.URL, refresh)
+					LogError(replaced.Save(handlerData.Db))
+				}
+			}
+
+			job.FileID = request.ReplaceFile
+			LogError(job.Done(handlerData.Db, message))
+			return
+		}
+
+		if status == http.StatusInternalServerError {
+			message = "internal server error"
+		}
+		LogError(job.Fail(handlerData.Db, errors.New(message)))
+		return
+	}
+
+	job.FileID = file.ID
+	job.Total = file.FileSize
+	LogError(job.Done(handlerData.Db, "downloaded "+file.Name))
+}
+
+//Store an upload described by request. read writes the content into the local file.
+//Returns the stored file or the http status and message to respond with
+func storeUpload(handlerData web.HandlerData, request models.UploadRequest, read func(io.Writer, *models.File) (int, string)) (*models.File, int, string) {
+	var err error
 	var namespace *models.Namespace
 	var file *models.File
 	var replaceMode bool
+	var oldLocalName string
+	var oldSize int64
 
 	if request.ReplaceFile > 0 {
 		replaceMode = true
@@ -96,12 +253,10 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		// Find file
 		file, err = models.FindFile(handlerData.Db, request.ReplaceFile, handlerData.User.ID)
 		if LogError(err) {
-			sendResponse(w, models.ResponseError, "File not found", nil, http.StatusNotFound)
-			return
+			return nil, http.StatusNotFound, "File not found"
 		}
 		if file == nil || file.Namespace == nil {
-			sendServerError(w)
-			return
+			return nil, http.StatusInternalServerError, ""
 		}
 
 		// Use new name if set
@@ -111,6 +266,18 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 
 		// Select namespace
 		namespace = file.Namespace
+
+		// Write new content into a new local file to keep the old one on errors
+		oldLocalName, oldSize = file.LocalName, file.FileSize
+		file.HasThumbnail = false
+
+		// Replacing a mirror by other content stops mirroring
+		if request.UploadType != models.URLUploadType {
+			file.StopMirror()
+		}
+		if !file.SetUniqueFilename(handlerData.Db) {
+			return nil, http.StatusInternalServerError, ""
+		}
 	} else {
 		if len(request.Name) == 0 {
 			request.Name = gaw.RandString(25)
@@ -126,26 +293,53 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		}
 
 		if !file.SetUniqueFilename(handlerData.Db) {
-			sendServerError(w)
-			return
+			return nil, http.StatusInternalServerError, ""
 		}
 	}
 
 	// Handle namespace errors (not found || no access)
-	if !handleNamespaceErorrs(namespace, handlerData.User, w) {
-		return
+	if !namespace.IsValid() {
+		return nil, http.StatusNotFound, "Namespace not found"
+	}
+	if !handlerData.User.HasAccess(namespace) {
+		return nil, http.StatusForbidden, "Write permission denied for this namespace"
 	}
 
 	// Set Tags, Groups and encryption
 	if len(request.Attributes.Tags) > 0 {
-		file.Tags = models.TagsFromStringArr(request.Attributes.Tags, *namespace, handlerData.User)
+		if replaceMode {
+			// Use existing tags, names are unique
+			for _, tag := range request.Attributes.Tags {
+				file.Tags = append(file.Tags, *models.GetTag(handlerData.Db, tag, namespace, handlerData.User))
+			}
+		} else {
+			file.Tags = models.TagsFromStringArr(request.Attributes.Tags, *namespace, handlerData.User)
+		}
 	}
 	if len(request.Attributes.Groups) > 0 {
-		file.Groups = models.GroupsFromStringArr(request.Attributes.Groups, *namespace, handlerData.User)
+		if replaceMode {
+			for _, group := range request.Attributes.Groups {
+				file.Groups = append(file.Groups, *models.GetGroup(handlerData.Db, group, namespace, handlerData.User))
+			}
+		} else {
+			file.Groups = models.GroupsFromStringArr(request.Attributes.Groups, *namespace, handlerData.User)
+		}
+	}
+	if len(request.Attributes.Description) > 0 {
+		file.Description = request.Attributes.Description
+	}
+	file.SetEncryption(request.Encryption, request.EncParams)
+
+	if !replaceMode {
+		// Check if namespace can hold one more file
+		if ok, err := namespace.CheckQuota(handlerData.Db, 0, 1); LogError(err) {
+			return nil, http.StatusInternalServerError, ""
+		} else if !ok {
+			return nil, http.StatusInsufficientStorage, "namespace quota exceeded"
+		}
 	}
-	file.SetEncryption(request.Encryption)
 
-	if request.Public {
+	if request.Public || (!replaceMode && namespace.DefaultPublic) {
 		// Determine public name
 		publicName := request.PublicName
 		if len(publicName) == 0 {
@@ -162,64 +356,84 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		// Check if public name already exists
 		_, found, _ := models.GetPublicFile(handlerData.Db, publicName)
 		if found {
-			sendResponse(w, models.ResponseError, "public name already exists", nil)
-			return
+			return nil, http.StatusConflict, "public name already exists"
 		}
 	}
 
 	// Create local file
-	f, err := os.Create(handlerData.Config.GetStorageFile(file.LocalName))
+	f, err := filestore.Create(handlerData.Config, file)
 	if LogError(err) {
-		sendServerError(w)
-		return
+		return nil, http.StatusInternalServerError, ""
 	}
 
-	// Read from the desired source (file/url)
-	switch request.UploadType {
-	case models.FileUploadType:
-		// Read from uploaded file
-		r.ParseMultipartForm(handlerData.User.Role.MaxUploadFileSize)
-
-		uploadfile, _, err := r.FormFile("uploadfile")
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		defer uploadfile.Close()
-
-		// Copy stream to file
-		size, err := io.Copy(f, uploadfile)
-		if LogError(err) {
-			sendServerError(w)
-			return
-		}
-
-		// Set filesize to written bytes
-		file.FileSize = int64(size)
-	case models.URLUploadType:
-		// Read from HTTP request
-		status, err := downloadHTTP(handlerData.User, request.URL, f, file)
-		if err != nil {
-			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusBadRequest)
-			return
-		}
-
-		// Check statuscode
-		if status > 299 || status < 200 {
-			sendResponse(w, models.ResponseError, "Non ok response: "+strconv.Itoa(status), nil, http.StatusBadRequest)
-			return
-		}
+	// Read content
+	if status, message := read(f, file); status != 0 {
+		f.Close()
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, status, message
 	}
 
 	// Close file
-	f.Close()
+	if LogError(f.Close()) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInternalServerError, ""
+	}
+
+	// Scan for malware
+	if status, message := scanUpload(handlerData, file); status != 0 {
+		return nil, status, message
+	}
 
 	// Detect mime type
-	mime, err := mimetype.DetectFile(handlerData.Config.GetStorageFile(file.LocalName))
+	mime, err := filestore.DetectMime(handlerData.Config, file)
 	if err != nil {
 		log.Info("Can't detect mime: ", err.Error())
 	} else {
-		file.FileType = strings.Split(mime.String(), ";")[0]
+		file.FileType = mime
+	}
+
+	// Check if filetype is allowed in namespace and for the role
+	if !file.CanUpload(handlerData.User) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusUnsupportedMediaType, "filetype not allowed"
+	}
+
+	// Check if filetype can be published
+	if file.IsPublic && !file.CanPublish(handlerData.User) {
+		if request.Public {
+			removeLocalFile(handlerData.Config, file.LocalName)
+			return nil, http.StatusUnsupportedMediaType, "filetype can't be published"
+		}
+
+		// Files public by default or before replacing them stay private
+		file.IsPublic = false
+		file.PublicFilename = sql.NullString{}
+	}
+
+	// Apply rules of namespace
+	rules, err := models.FindRules(handlerData.Db, namespace)
+	if LogError(err) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInternalServerError, ""
+	}
+	file.ApplyRules(handlerData.Db, rules, handlerData.User)
+
+	// Apply default tags, groups and expiry of namespace
+	if !replaceMode {
+		file.ApplyNamespaceDefaults(namespace, handlerData.User)
+	}
+
+	// Check namespace size quota
+	addFiles := int64(1)
+	if replaceMode {
+		addFiles = 0
+	}
+	if ok, err := namespace.CheckQuota(handlerData.Db, file.FileSize-oldSize, addFiles); err != nil || !ok {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		if LogError(err) {
+			return nil, http.StatusInternalServerError, ""
+		}
+		return nil, http.StatusInsufficientStorage, "namespace quota exceeded"
 	}
 
 	if replaceMode {
@@ -229,16 +443,34 @@ func UploadfileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *ht
 		// Insert file to DB
 		err = file.Insert(handlerData.Db, handlerData.User)
 	}
+	if LogError(err) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return nil, http.StatusInternalServerError, ""
+	}
 
-	if !LogError(err) {
-		sendResponse(w, models.ResponseSuccess, "", models.UploadResponse{
-			FileID:         file.ID,
-			Filename:       file.Name,
-			PublicFilename: file.PublicFilename.String,
-		})
-	} else {
-		sendServerError(w)
+	// Set metadata
+	if LogError(file.SetMeta(handlerData.Db, request.Attributes.Meta)) {
+		if replaceMode {
+			// The new content is already referenced
+			go models.ShredLocalFile(handlerData.Config, oldLocalName)
+		} else {
+			LogError(file.Delete(handlerData.Db, handlerData.Config))
+		}
+		return nil, http.StatusInternalServerError, ""
+	}
+
+	// Remove replaced content
+	if replaceMode {
+		go models.ShredLocalFile(handlerData.Config, oldLocalName)
 	}
+
+	// Index content for full-text search and create thumbnails
+	go (func(file models.File) {
+		LogError(search.IndexFile(handlerData.Db, handlerData.Config, file))
+		LogError(thumbnail.Generate(handlerData.Db, handlerData.Config, file))
+	})(*file)
+
+	return file, 0, ""
 }
 
 // ListFilesHandler handler for listing files
@@ -260,15 +492,25 @@ func ListFilesHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 		}
 	}
 
-	var foundFiles []models.File
+	// Validate order
+	if !models.IsValidFileOrder(request.OptionalParams.OrderBy) {
+		sendResponse(w, models.ResponseError, "invalid order", nil, http.StatusUnprocessableEntity)
+		return
+	}
 
-	loaded := handlerData.Db.Model(&foundFiles)
-	if len(request.Attributes.Tags) > 0 || request.OptionalParams.Verbose > 1 {
-		loaded = loaded.Preload("Tags")
+	// Parse query
+	var query *models.FileQuery
+	if len(request.Query) > 0 {
+		var err error
+		if query, err = models.ParseFileQuery(request.Query); err != nil {
+			sendResponse(w, models.ResponseError, "invalid query: "+err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
 	}
 
-	if len(request.Attributes.Groups) > 0 || request.OptionalParams.Verbose > 1 {
-		loaded = loaded.Preload("Groups")
+	loaded := handlerData.Db.Model(&models.File{})
+	if request.OptionalParams.Verbose > 1 {
+		loaded = loaded.Preload("Tags").Preload("Groups").Preload("Meta")
 	}
 
 	if request.OptionalParams.Verbose > 2 || request.AllNamespaces {
@@ -286,12 +528,30 @@ func ListFilesHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 			Where("namespaces.creator = ?", handlerData.User.ID)
 	} else {
 		// Just select the specified namespace
-		loaded = loaded.Where("namespace_id = ?", namespace.ID)
+		loaded = loaded.Where("files.namespace_id = ?", namespace.ID)
+	}
+
+	// Filter tags, groups and query
+	loaded = models.FilterFilesByTags(loaded, request.Attributes.Tags)
+	loaded = models.FilterFilesByGroups(loaded, request.Attributes.Groups)
+	loaded = query.Apply(loaded)
+
+	// Filter metadata
+	loaded, err := models.FilterFilesByMeta(loaded, request.Attributes.Meta)
+	if err != nil {
+		sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+		return
 	}
 
 	// Search
-	err := loaded.Find(&foundFiles).Error
-	if LogError(err) {
+	foundFiles, nextCursor, total, err := models.PaginateFiles(loaded, request.OptionalParams)
+	if err != nil {
+		if err == models.ErrInvalidCursor {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+
+		LogError(err)
 		sendServerError(w)
 		return
 	}
@@ -299,40 +559,62 @@ func ListFilesHandler(handlerData web.HandlerData, w http.ResponseWriter, r *htt
 	// Convert to ResponseFile
 	var retFiles []models.FileResponseItem
 	for _, file := range foundFiles {
-		// Filter tags
-		if (len(request.Attributes.Tags) == 0 || (len(request.Attributes.Tags) > 0 && file.IsInTagList(request.Attributes.Tags))) &&
-			// Filter groups
-			(len(request.Attributes.Groups) == 0 || (len(request.Attributes.Groups) > 0 && file.IsInGroupList(request.Attributes.Groups))) {
-			respItem := models.FileResponseItem{
-				ID:           file.ID,
-				Name:         file.Name,
-				CreationDate: file.CreatedAt,
-				Size:         file.FileSize,
-				IsPublic:     file.IsPublic,
-			}
+		respItem := models.FileResponseItem{
+			ID:           file.ID,
+			Name:         file.Name,
+			CreationDate: file.CreatedAt,
+			Size:         file.FileSize,
+			IsPublic:     file.IsPublic,
+		}
 
-			// Set encryption
-			if file.Encryption.Valid && constants.EncryptionIValid(file.Encryption.Int32) {
-				respItem.Encryption = constants.ChiperToString(file.Encryption.Int32)
-			}
+		// Set encryption
+		respItem.Encryption, respItem.EncParams = file.GetEncryption()
 
-			// Append public name if available
-			if file.PublicFilename.Valid && len(file.PublicFilename.String) > 0 {
-				respItem.PublicName = file.PublicFilename.String
-			}
+		// Set expiry and thumbnail
+		respItem.Expiry = file.ExpiresAt
+		respItem.Thumbnail = file.HasThumbnail
 
-			// Return attributes on verbose
-			if request.OptionalParams.Verbose > 1 || request.AllNamespaces {
-				respItem.Attributes = file.GetAttributes()
-			}
+		// Set source of mirrors
+		if file.IsMirror() {
+			respItem.Mirror = file.SourceURL
+			respItem.Refresh = file.RefreshInterval.String()
+		}
 
-			// Add if matching filter
-			retFiles = append(retFiles, respItem)
+		// Set signature of infected files
+		if file.Quarantined {
+			respItem.Infected = file.Signature
 		}
+
+		// Return description on verbose
+		if request.OptionalParams.Verbose > 0 {
+			respItem.Description = file.Description
+		}
+
+		// Append public name if available
+		if file.PublicFilename.Valid && len(file.PublicFilename.String) > 0 {
+			respItem.PublicName = file.PublicFilename.String
+		}
+
+		// Return attributes on verbose
+		if request.OptionalParams.Verbose > 1 || request.AllNamespaces {
+			respItem.Attributes = file.GetAttributes()
+		}
+
+		retFiles = append(retFiles, respItem)
+	}
+
+	// Tell clients not paginating that the list is incomplete
+	var message string
+	truncated := request.OptionalParams.Limit == 0 && len(nextCursor) > 0
+	if truncated {
+		message = fmt.Sprintf("only the first %d files are listed, use the cursor to get more", models.MaxListLimit)
 	}
 
-	sendResponse(w, models.ResponseSuccess, "", models.ListFileResponse{
-		Files: retFiles,
+	sendResponse(w, models.ResponseSuccess, message, models.ListFileResponse{
+		Files:      retFiles,
+		NextCursor: nextCursor,
+		Truncated:  truncated,
+		Total:      total,
 	})
 }
 
@@ -343,12 +625,25 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Filter by tags, groups or query
+	hasFilter := len(request.Attributes.Tags) > 0 || len(request.Attributes.Groups) > 0 || len(request.Query) > 0
+
 	// Validate input
-	if len(request.Name) == 0 && request.FileID <= 0 {
+	if len(request.Name) == 0 && request.FileID <= 0 && !(request.All && hasFilter) {
 		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
 		return
 	}
 
+	// Parse query
+	var query *models.FileQuery
+	if len(request.Query) > 0 {
+		var err error
+		if query, err = models.ParseFileQuery(request.Query); err != nil {
+			sendResponse(w, models.ResponseError, "invalid query: "+err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
 	// Get action
 	vars := mux.Vars(r)
 	action, has := vars["action"]
@@ -357,10 +652,14 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Getting all files is not allowed
+	// Getting all files is only possible as archive
+	var archiveFormat web.ArchiveFormat
 	if request.All && action == "get" {
-		sendResponse(w, models.ResponseError, "Illegal request", nil)
-		return
+		var err error
+		if archiveFormat, err = web.ParseArchiveFormat(request.Archive); err != nil {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
 	}
 
 	var namespace *models.Namespace
@@ -389,7 +688,7 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 		},
 		Name:      request.Name,
 		Namespace: namespace,
-	})
+	}, query, request.Attributes.Tags, request.Attributes.Groups)
 
 	if LogError(err) {
 		sendServerError(w)
@@ -440,26 +739,40 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 	case "update":
 		{
 			var count uint32
+			update := request.Updates
+
+			// Get new namespace
+			var newNamespace *models.Namespace
+			if len(update.NewNamespace) > 0 {
+				newNamespace = models.FindNamespace(handlerData.Db, update.NewNamespace, handlerData.User)
+				if newNamespace == nil || newNamespace.ID == 0 {
+					sendResponse(w, models.ResponseError, "New namespace not found", nil, http.StatusNotFound)
+					return
+				}
+
+				// Check if user can access this new namespace
+				if !newNamespace.IsOwnedBy(handlerData.User) && !handlerData.User.CanWriteForeignNamespace() {
+					sendResponse(w, models.ResponseError, "Write permission denied for foreign namespaces", nil, http.StatusForbidden)
+					return
+				}
+
+				// Check quota and allowed filetypes of the new namespace
+				if err := newNamespace.CheckMove(handlerData.Db, files); err == models.ErrNamespaceQuotaExceeded {
+					sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusInsufficientStorage)
+					return
+				} else if err == models.ErrNamespaceTypeDenied {
+					sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnsupportedMediaType)
+					return
+				} else if LogError(err) {
+					sendServerError(w)
+					return
+				}
+			}
 
 			// Do for every file
 			for _, file := range files {
-				update := request.Updates
-
 				// Update namespace
-				if len(update.NewNamespace) > 0 {
-					// Get new namespace
-					newNamespace := models.FindNamespace(handlerData.Db, update.NewNamespace, handlerData.User)
-					if newNamespace == nil || namespace.ID == 0 {
-						sendResponse(w, models.ResponseError, "New namespace not found", nil, http.StatusNotFound)
-						return
-					}
-
-					// Check if user can access this new namespace
-					if !newNamespace.IsOwnedBy(handlerData.User) && !handlerData.User.CanWriteForeignNamespace() {
-						sendResponse(w, models.ResponseError, "Write permission denied for foreign namespaces", nil, http.StatusForbidden)
-						return
-					}
-
+				if newNamespace != nil {
 					// Update files namespace
 					err := file.UpdateNamespace(handlerData.Db, newNamespace, handlerData.User)
 					if LogError(err) {
@@ -492,6 +805,16 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 						return
 					}
 
+					if newVisibility && file.Quarantined {
+						sendResponse(w, models.ResponseError, "File is quarantined", nil, http.StatusForbidden)
+						return
+					}
+
+					if newVisibility && !file.CanPublish(handlerData.User) {
+						sendResponse(w, models.ResponseError, "Filetype can't be published", nil, http.StatusUnsupportedMediaType)
+						return
+					}
+
 					if LogError(file.SetVilibility(handlerData.Db, newVisibility)) {
 						sendServerError(w)
 						return
@@ -539,6 +862,60 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 					didUpdate = len(file.Groups) < currLenGroups
 				}
 
+				// Set description
+				if update.Description != nil && *update.Description != file.Description {
+					file.Description = *update.Description
+					if LogError(file.Save(handlerData.Db)) {
+						sendServerError(w)
+						return
+					}
+					didUpdate = true
+				}
+
+				// Change refresh interval of mirrors. Empty stops refreshing
+				if update.Refresh != nil && len(file.SourceURL) > 0 {
+					refresh, err := models.ParseRefreshInterval(*update.Refresh)
+					if err != nil {
+						sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+						return
+					}
+
+					if refresh > 0 {
+						file.SetMirror(file.SourceURL, refresh)
+					} else {
+						file.StopMirror()
+					}
+
+					if LogError(file.Save(handlerData.Db)) {
+						sendServerError(w)
+						return
+					}
+					didUpdate = true
+				}
+
+				// Set metadata
+				if len(update.SetMeta) > 0 {
+					if err := models.ValidateMeta(update.SetMeta); err != nil {
+						sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+						return
+					}
+					if LogError(file.SetMeta(handlerData.Db, update.SetMeta)) {
+						sendServerError(w)
+						return
+					}
+					didUpdate = true
+				}
+
+				// Remove metadata
+				if len(update.RemoveMeta) > 0 {
+					currLenMeta := len(file.Meta)
+					if LogError(file.RemoveMeta(handlerData.Db, update.RemoveMeta)) {
+						sendServerError(w)
+						return
+					}
+					didUpdate = didUpdate || len(file.Meta) < currLenMeta
+				}
+
 				// Only count if updated
 				if didUpdate {
 					count++
@@ -553,11 +930,49 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 	// Get file
 	case "get":
 		{
+			// Stream all files as archive. Quarantined and expired files are skipped
+			if request.All {
+				var archiveFiles []models.File
+				for i := range files {
+					if !files[i].Quarantined && !files[i].IsExpired() {
+						archiveFiles = append(archiveFiles, files[i])
+					}
+				}
+
+				LogError(web.ServeFilesArchive(handlerData.Config, w, archiveFiles, archiveFormat, namespace.Name))
+				return
+			}
+
 			// Use first file
 			file := files[0]
+			if file.Quarantined {
+				sendResponse(w, models.ResponseError, "File is quarantined: "+file.Signature, nil, http.StatusForbidden)
+				return
+			}
+			if file.IsExpired() {
+				sendResponse(w, models.ResponseError, "File expired", nil, http.StatusNotFound)
+				return
+			}
+
+			// Use thumbnail if requested
+			localFile := handlerData.Config.GetStorageFile(file.LocalName)
+			if len(request.Thumbnail) > 0 {
+				if !models.IsValidThumbnailSize(request.Thumbnail) {
+					sendResponse(w, models.ResponseError, "invalid thumbnail size", nil, http.StatusUnprocessableEntity)
+					return
+				}
+
+				if !file.HasThumbnail {
+					sendResponse(w, models.ResponseError, "File has no thumbnail", nil, http.StatusNotFound)
+					return
+				}
+
+				localFile = handlerData.Config.GetThumbnailFile(file.LocalName, request.Thumbnail)
+				file.FileType = ""
+			}
 
 			// Open local file
-			f, err := os.Open(handlerData.Config.GetStorageFile(file.LocalName))
+			f, err := filestore.OpenPath(&file, localFile)
 			if LogError(err) {
 				if os.IsNotExist(err) {
 					sendResponse(w, models.ResponseError, "File not found on server", nil, 404)
@@ -576,9 +991,17 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 			// Set filename header
 			w.Header().Set(models.HeaderFileName, file.Name)
 
-			// Set encryption cipher header
-			if file.Encryption.Valid {
-				w.Header().Set(models.HeaderEncryption, constants.ChiperToString(file.Encryption.Int32))
+			// Set encryption cipher headers
+			if cipher, params := file.GetEncryption(); len(cipher) > 0 {
+				w.Header().Set(models.HeaderEncryption, cipher)
+
+				if len(params) > 0 {
+					values := url.Values{}
+					for key, value := range params {
+						values.Set(key, value)
+					}
+					w.Header().Set(models.HeaderEncryptionParams, values.Encode())
+				}
 			}
 
 			// Write contents to responsewriter
@@ -608,6 +1031,24 @@ func FileHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Req
 					continue
 				}
 
+				// Infected files can't be published
+				if file.Quarantined {
+					if len(files) == 1 {
+						sendResponse(w, models.ResponseError, "File is quarantined", nil, http.StatusForbidden)
+						return
+					}
+					continue
+				}
+
+				// Skip types not allowed to be published
+				if !file.CanPublish(handlerData.User) {
+					if len(files) == 1 {
+						sendResponse(w, models.ResponseError, "Filetype can't be published", nil, http.StatusUnsupportedMediaType)
+						return
+					}
+					continue
+				}
+
 				nameTaken, err := file.Publish(handlerData.Db, request.PublicName)
 				if err != nil {
 					sendServerError(w)
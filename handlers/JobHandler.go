@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+//JobHandler returns the state of a job
+func JobHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	job := findRequestJob(handlerData, w, r)
+	if job == nil {
+		return
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", job.ToResponse())
+}
+
+//CancelJobHandler cancels a running job
+func CancelJobHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	job := findRequestJob(handlerData, w, r)
+	if job == nil {
+		return
+	}
+
+	//Only downloads and rescans can be stopped
+	if job.Type != models.URLDownloadJobType && job.Type != models.RescanJobType {
+		sendResponse(w, models.ResponseError, "Job can't be canceled", nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if job.IsFinished() {
+		sendResponse(w, models.ResponseError, "Job already finished", nil, http.StatusConflict)
+		return
+	}
+
+	//Jobs not running on this server (eg. after a restart) are canceled directly
+	if !models.CancelJob(job.ID) {
+		if err := job.Cancel(handlerData.Db); LogError(err) {
+			sendServerError(w)
+			return
+		}
+	} else {
+		job.State = models.JobCanceled
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", job.ToResponse())
+}
+
+//Find the job of the requested id. Returns nil if not found and sends an error
+func findRequestJob(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) *models.Job {
+	vars := mux.Vars(r)
+	jobID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return nil
+	}
+
+	//Find job
+	job, err := models.FindJob(handlerData.Db, uint(jobID), handlerData.User)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			sendResponse(w, models.ResponseError, "Job not found", nil, http.StatusNotFound)
+			return nil
+		}
+
+		LogError(err)
+		sendServerError(w)
+		return nil
+	}
+
+	return job
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+//Max files refreshed per run
+const maxMirrorRefreshs = 100
+
+//Set to 1 while mirrors are refreshed
+var refreshingMirrors int32
+
+//RefreshMirrors downloads mirrored files again if their refresh interval passed.
+//Files are only replaced if the content changed. Does nothing if a refresh is already running
+func RefreshMirrors(config *models.Config, db *gorm.DB) {
+	if !atomic.CompareAndSwapInt32(&refreshingMirrors, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&refreshingMirrors, 0)
+
+	files, err := models.FindFilesToRefresh(db, maxMirrorRefreshs)
+	if LogError(err) {
+		return
+	}
+
+	var updated int
+	for i := range files {
+		file := &files[i]
+
+		// Files of deleted users can't be refreshed
+		if file.User == nil || file.User.Role == nil {
+			file.StopMirror()
+			LogError(file.Save(db))
+			continue
+		}
+
+		changed, err := refreshMirror(config, db, file)
+		if err != nil {
+			log.Warnf("Can't refresh file %d from %s: %s", file.ID, file.SourceURL, err)
+		} else if changed {
+			updated++
+		}
+
+		// Schedule next refresh on errors too to not retry every minute
+		LogError(file.ScheduleRefresh(db))
+	}
+
+	if updated > 0 {
+		log.Infof("Refreshed %d mirrored files", updated)
+	}
+}
+
+//Refresh a mirrored file. Return true if the content changed
+func refreshMirror(config *models.Config, db *gorm.DB, mirror *models.File) (bool, error) {
+	handlerData := web.HandlerData{
+		Config: config,
+		Db:     db,
+		User:   mirror.User,
+	}
+
+	request := models.UploadRequest{
+		UploadType:  models.URLUploadType,
+		URL:         mirror.SourceURL,
+		ReplaceFile: mirror.ID,
+	}
+
+	file, status, message := storeUpload(handlerData, request, func(f io.Writer, file *models.File) (int, string) {
+		status, err := downloadHTTP(context.Background(), config, db, nil, mirror.User, mirror.SourceURL, f, file)
+		if err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+
+		// Content didn't change
+		if status == http.StatusNotModified {
+			return status, ""
+		}
+
+		if status > 299 || status < 200 {
+			return http.StatusBadRequest, "Non ok response: " + strconv.Itoa(status)
+		}
+
+		return 0, ""
+	})
+
+	if file == nil {
+		if status == http.StatusNotModified {
+			return false, nil
+		}
+
+		if len(message) == 0 {
+			message = http.StatusText(status)
+		}
+		return false, errors.New(message)
+	}
+
+	return true, nil
+}
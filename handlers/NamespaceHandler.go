@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -79,6 +80,12 @@ func NamespaceActionHandler(handlerData web.HandlerData, w http.ResponseWriter,
 			sendResponse(w, models.ResponseError, "namespace not found", nil, http.StatusNotFound)
 			return
 		}
+
+		//Check if user can access this namespace
+		if !handlerData.User.HasAccess(namespace) {
+			sendResponse(w, models.ResponseError, "Write permission denied for this namespace", nil, http.StatusForbidden)
+			return
+		}
 	}
 
 	var err error
@@ -86,26 +93,68 @@ func NamespaceActionHandler(handlerData web.HandlerData, w http.ResponseWriter,
 	switch action {
 	case "create":
 		{
-			// Create namespaceo
-			err = handlerData.Db.Model(&models.Namespace{}).Create(&models.Namespace{
+			newNamespace := models.Namespace{
 				Name:   namespaceName,
 				User:   handlerData.User,
 				UserID: handlerData.User.ID,
-			}).Error
+			}
+
+			// Apply settings if set
+			if len(request.Settings) > 0 {
+				if !applyNamespaceSettings(&newNamespace, request.Settings, w) {
+					return
+				}
+			}
+
+			// Create namespaceo
+			err = handlerData.Db.Model(&models.Namespace{}).Create(&newNamespace).Error
 		}
 	case "update":
 		{
+			// Rename namespace
+			if len(request.NewName) > 0 {
+				namespace.Name = request.NewName
+			}
+
+			// Update settings
+			if len(request.Settings) > 0 {
+				if !applyNamespaceSettings(namespace, request.Settings, w) {
+					return
+				}
+			}
+
 			// Update namespace
-			namespace.Name = request.NewName
 			err = handlerData.Db.Model(&models.Namespace{}).Save(namespace).Error
 		}
 	case "delete":
 		{
-			// Delete namespace
-			handlerData.Db.Delete(namespace)
-			handlerData.Db.Delete(&models.Tag{}, "namespace_id=?", namespace.ID)
-			handlerData.Db.Delete(&models.Group{}, "namespace_id=?", namespace.ID)
-			handlerData.Db.Delete(&models.File{}, "namespace_id=?", namespace.ID)
+			// Find namespace to move the files into
+			var moveTo *models.Namespace
+			if len(request.MoveTo) > 0 {
+				moveTo = models.FindNamespace(handlerData.Db, request.MoveTo, handlerData.User)
+				if !handleNamespaceErorrs(moveTo, handlerData.User, w) {
+					return
+				}
+
+				if moveTo.ID == namespace.ID {
+					sendResponse(w, models.ResponseError, "can't move files into the deleted namespace", nil, http.StatusBadRequest)
+					return
+				}
+			}
+
+			job, err := models.NewJob(handlerData.Db, models.NamespaceDeleteJobType, handlerData.User)
+			if LogError(err) {
+				sendServerError(w)
+				return
+			}
+
+			// Delete namespace in background
+			go (func() {
+				LogError(namespace.Delete(handlerData.Db, handlerData.Config, job, moveTo, handlerData.User))
+			})()
+
+			sendResponse(w, models.ResponseSuccess, "", job.ToResponse(), http.StatusAccepted)
+			return
 		}
 	}
 
@@ -137,3 +186,24 @@ func NamespaceListHandler(handlerData web.HandlerData, w http.ResponseWriter, r
 		Slice: snamespaces,
 	})
 }
+
+//Apply the sent settings. Settings which weren't sent are kept. Return false on error
+func applyNamespaceSettings(namespace *models.Namespace, rawSettings json.RawMessage, w http.ResponseWriter) bool {
+	settings := namespace.GetSettings()
+	if err := json.Unmarshal(rawSettings, &settings); err != nil {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return false
+	}
+
+	if settings.MaxSize < 0 || settings.MaxFiles < 0 {
+		sendResponse(w, models.ResponseError, "quota can't be negative", nil, http.StatusUnprocessableEntity)
+		return false
+	}
+
+	if err := namespace.ApplySettings(settings); err != nil {
+		sendResponse(w, models.ResponseError, "invalid expiry", nil, http.StatusUnprocessableEntity)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+)
+
+//RawUploadHandler handler for uploading a raw request body like 'curl --upload-file'.
+//Options are passed as query parameters or headers. Responds the public link as plain text
+func RawUploadHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	// Check if user is allowed to upload files
+	if !handlerData.User.CanUploadFiles() {
+		sendRawUploadResponse(w, "not allowed to upload files", http.StatusForbidden)
+		return
+	}
+
+	request := models.UploadRequest{
+		UploadType: models.FileUploadType,
+		Name:       mux.Vars(r)["name"],
+		Public:     true,
+		PublicName: rawUploadOption(r, "pbname", "X-Public-Name"),
+		Attributes: models.FileAttributes{
+			Namespace:   rawUploadOption(r, "ns", "X-Namespace"),
+			Tags:        splitRawUploadOption(rawUploadOption(r, "tags", "X-Tags")),
+			Groups:      splitRawUploadOption(rawUploadOption(r, "groups", "X-Groups")),
+			Description: rawUploadOption(r, "desc", "X-Description"),
+		},
+	}
+
+	// Files are public unless disabled
+	if public := rawUploadOption(r, "public", "X-Public"); len(public) > 0 {
+		isPublic, err := strconv.ParseBool(public)
+		if err != nil {
+			sendRawUploadResponse(w, "invalid value for public", http.StatusUnprocessableEntity)
+			return
+		}
+		request.Public = isPublic
+	}
+
+	// Use role limit if set, otherwise the server limit
+	maxSize := handlerData.Config.Webserver.MaxUploadFileLength
+	if roleMax := handlerData.User.Role.MaxUploadFileSize; roleMax > 0 {
+		maxSize = roleMax
+	}
+
+	file, status, message := storeUpload(handlerData, request, func(f io.Writer, file *models.File) (int, string) {
+		// Read one more byte to detect too large bodies
+		size, err := io.Copy(f, io.LimitReader(r.Body, maxSize+1))
+		if LogError(err) {
+			return http.StatusInternalServerError, ""
+		}
+
+		if size > maxSize {
+			return http.StatusRequestEntityTooLarge, "file too large"
+		}
+
+		file.FileSize = size
+		return 0, ""
+	})
+
+	if file == nil {
+		if status == http.StatusInternalServerError {
+			message = "internal server error"
+		}
+		sendRawUploadResponse(w, message, status)
+		return
+	}
+
+	// Respond the link if public, otherwise the ID
+	if file.IsPublic {
+		sendRawUploadResponse(w, "https://"+r.Host+"/preview/"+file.PublicFilename.String, http.StatusCreated)
+	} else {
+		sendRawUploadResponse(w, strconv.FormatUint(uint64(file.ID), 10), http.StatusCreated)
+	}
+}
+
+//Get an option of a raw upload from the query or, if not set, from a header
+func rawUploadOption(r *http.Request, key, header string) string {
+	if value := r.URL.Query().Get(key); len(value) > 0 {
+		return value
+	}
+	return r.Header.Get(header)
+}
+
+//Split a comma separated option
+func splitRawUploadOption(option string) []string {
+	var items []string
+	for _, item := range strings.Split(option, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+//Send a plain text response. Shells print it as it is
+func sendRawUploadResponse(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	fmt.Fprintln(w, message)
+}
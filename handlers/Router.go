@@ -31,6 +31,7 @@ const (
 	GetMethod    HTTPMethod = "GET"
 	POSTMethod   HTTPMethod = "POST"
 	DeleteMethod HTTPMethod = "DELETE"
+	PUTMethod    HTTPMethod = "PUT"
 )
 
 type requestType uint8
@@ -82,6 +83,20 @@ var (
 			HandlerFunc: UploadfileHandler,
 			HandlerType: sessionRequest,
 		},
+		Route{
+			Name:        "raw upload",
+			Pattern:     "/upload/{name}",
+			Method:      PUTMethod,
+			HandlerFunc: RawUploadHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "raw upload post",
+			Pattern:     "/upload/{name}",
+			Method:      POSTMethod,
+			HandlerFunc: RawUploadHandler,
+			HandlerType: sessionRequest,
+		},
 		Route{
 			Name:        "list files",
 			Pattern:     "/files",
@@ -89,6 +104,20 @@ var (
 			HandlerFunc: ListFilesHandler,
 			HandlerType: sessionRequest,
 		},
+		Route{
+			Name:        "search",
+			Pattern:     "/search",
+			Method:      POSTMethod,
+			HandlerFunc: SearchHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "collections",
+			Pattern:     "/collections/{action}",
+			Method:      POSTMethod,
+			HandlerFunc: CollectionHandler,
+			HandlerType: sessionRequest,
+		},
 		Route{
 			Name:        "fileaction",
 			Pattern:     "/file/{action}",
@@ -112,8 +141,64 @@ var (
 			HandlerType: defaultRequest,
 			Method:      GetMethod,
 		},
+		Route{
+			Name:        "collection",
+			Pattern:     "/collection/{name}",
+			HandlerFunc: web.CollectionPageHandler,
+			HandlerType: defaultRequest,
+			Method:      GetMethod,
+		},
+		Route{
+			Name:        "collection password",
+			Pattern:     "/collection/{name}",
+			HandlerFunc: web.CollectionPageHandler,
+			HandlerType: defaultRequest,
+			Method:      POSTMethod,
+		},
+		Route{
+			Name:        "collection archive",
+			Pattern:     "/collection/{name}/archive",
+			HandlerFunc: web.CollectionArchiveHandler,
+			HandlerType: defaultRequest,
+			Method:      GetMethod,
+		},
+		Route{
+			Name:        "collection file",
+			Pattern:     "/collection/{name}/file/{fileID}",
+			HandlerFunc: web.CollectionFileHandler,
+			HandlerType: defaultRequest,
+			Method:      GetMethod,
+		},
+		Route{
+			Name:        "oembed",
+			Pattern:     "/oembed",
+			HandlerFunc: web.OEmbedHandler,
+			HandlerType: defaultRequest,
+			Method:      GetMethod,
+		},
+		Route{
+			Name:        "thumbnail",
+			Pattern:     "/preview/thumb/{fileID}",
+			HandlerFunc: web.ThumbnailHandler,
+			HandlerType: defaultRequest,
+			Method:      GetMethod,
+		},
 
 		// Attribute
+		Route{
+			Name:        "Tag tree",
+			Pattern:     "/tags/tree",
+			Method:      POSTMethod,
+			HandlerFunc: TagTreeHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "Attribute list",
+			Pattern:     "/attributes/{attribute}",
+			Method:      POSTMethod,
+			HandlerFunc: ListAttributesHandler,
+			HandlerType: sessionRequest,
+		},
 		Route{
 			Name:        "Attribute",
 			Pattern:     "/attribute/{attribute}/{action}",
@@ -122,7 +207,37 @@ var (
 			HandlerType: sessionRequest,
 		},
 
+		//Admin
+		Route{
+			Name:        "Rescan",
+			Pattern:     "/admin/rescan",
+			Method:      POSTMethod,
+			HandlerFunc: RescanHandler,
+			HandlerType: sessionRequest,
+		},
+
 		//Namespace
+		Route{
+			Name:        "Namespace export",
+			Pattern:     "/namespace/export",
+			Method:      POSTMethod,
+			HandlerFunc: ExportNamespaceHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "Namespace import",
+			Pattern:     "/namespace/import",
+			Method:      POSTMethod,
+			HandlerFunc: ImportNamespaceHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "Namespace rules",
+			Pattern:     "/namespace/rules/{action}",
+			Method:      POSTMethod,
+			HandlerFunc: NamespaceRuleHandler,
+			HandlerType: sessionRequest,
+		},
 		Route{
 			Name:        "Namespace",
 			Pattern:     "/namespace/{action}",
@@ -137,6 +252,22 @@ var (
 			HandlerFunc: NamespaceListHandler,
 			HandlerType: sessionRequest,
 		},
+
+		//Jobs
+		Route{
+			Name:        "Job",
+			Pattern:     "/jobs/{id}",
+			Method:      POSTMethod,
+			HandlerFunc: JobHandler,
+			HandlerType: sessionRequest,
+		},
+		Route{
+			Name:        "Job cancel",
+			Pattern:     "/jobs/{id}/cancel",
+			Method:      POSTMethod,
+			HandlerFunc: CancelJobHandler,
+			HandlerType: sessionRequest,
+		},
 	}
 )
 
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/gaw"
+	"github.com/gorilla/mux"
+)
+
+//NamespaceRuleHandler handler for rules of a namespace (list/add/update/delete/apply)
+func NamespaceRuleHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	if !gaw.IsInStringArray(action, []string{"list", "add", "update", "delete", "apply"}) {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	var request models.RuleRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	// Select namespace
+	namespace := models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+
+	// Handle namespace errors (not found || no access)
+	if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+		return
+	}
+
+	switch action {
+	case "list":
+		rules, err := models.FindRules(handlerData.Db, namespace)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+
+		response := models.RuleListResponse{
+			Rules: make([]models.RuleSettings, len(rules)),
+		}
+		for i := range rules {
+			response.Rules[i] = rules[i].GetSettings()
+		}
+
+		sendResponse(w, models.ResponseSuccess, "", response)
+		return
+	case "apply":
+		// Applying rules changes all files of the namespace
+		if !namespace.IsOwnedBy(handlerData.User) && !handlerData.User.Role.IsAdmin {
+			sendResponse(w, models.ResponseError, "Only the owner of the namespace can apply its rules", nil, http.StatusForbidden)
+			return
+		}
+
+		job, err := models.NewJob(handlerData.Db, models.RuleApplyJobType, handlerData.User)
+		if LogError(err) {
+			sendServerError(w)
+			return
+		}
+
+		// Apply rules to existing files in background
+		go (func() {
+			LogError(models.ApplyRulesToNamespace(handlerData.Db, job, namespace, handlerData.User))
+		})()
+
+		sendResponse(w, models.ResponseSuccess, "", job.ToResponse(), http.StatusAccepted)
+		return
+	}
+
+	if request.Rule == nil {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	rule := &models.Rule{
+		NamespaceID: namespace.ID,
+	}
+
+	// Find existing rule
+	if action != "add" {
+		var err error
+		rule, err = models.FindRule(handlerData.Db, request.Rule.ID, namespace)
+		if err != nil {
+			sendResponse(w, models.ResponseError, "Rule not found", nil, http.StatusNotFound)
+			return
+		}
+	}
+
+	var err error
+	if action == "delete" {
+		err = handlerData.Db.Delete(rule).Error
+	} else {
+		if err = rule.ApplySettings(*request.Rule); err != nil {
+			sendResponse(w, models.ResponseError, err.Error(), nil, http.StatusUnprocessableEntity)
+			return
+		}
+
+		err = handlerData.Db.Save(rule).Error
+	}
+
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", rule.GetSettings())
+}
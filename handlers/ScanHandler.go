@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/DataManagerServer/scanner"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+//RescanHandler scans existing files for malware again. Only admins are allowed to do this
+func RescanHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	if !handlerData.User.Role.IsAdmin {
+		sendResponse(w, models.ResponseError, "Admin permission required", nil, http.StatusForbidden)
+		return
+	}
+
+	if !scanner.IsEnabled(handlerData.Config) {
+		sendResponse(w, models.ResponseError, "Scanning is disabled", nil, http.StatusUnprocessableEntity)
+		return
+	}
+
+	var request models.RescanRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	// Select files. Quarantined files are skipped
+	query := handlerData.Db.Model(&models.File{}).Where("quarantined = false")
+	switch {
+	case len(request.Files) > 0:
+		query = query.Where("id IN (?)", request.Files)
+	case len(request.Namespace) > 0:
+		namespace := models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+		if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+			return
+		}
+		query = query.Where("namespace_id = ?", namespace.ID)
+	case !request.All:
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	var files []models.File
+	err := query.Preload("User").Preload("Namespace").Find(&files).Error
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	job, err := models.NewJob(handlerData.Db, models.RescanJobType, handlerData.User)
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	// Scan in background
+	go rescanFiles(handlerData.Config, handlerData.Db, job, files)
+
+	sendResponse(w, models.ResponseSuccess, "", job.ToResponse(), http.StatusAccepted)
+}
+
+//Scan files and quarantine infected ones. The state is reported in job
+func rescanFiles(config *models.Config, db *gorm.DB, job *models.Job, files []models.File) {
+	ctx, release := job.WithCancel()
+	defer release()
+
+	if LogError(job.Start(db, int64(len(files)))) {
+		return
+	}
+
+	var infected, failed int
+	for i := range files {
+		if ctx.Err() != nil {
+			LogError(job.Cancel(db))
+			return
+		}
+
+		result, err := scanner.ScanFile(config, &files[i])
+		if err != nil {
+			log.Warnf("Can't scan file %d: %s", files[i].ID, err)
+			failed++
+		} else if result != nil && result.Infected {
+			log.Warnf("File %d is infected: %s", files[i].ID, result.Signature)
+			if LogError(files[i].Quarantine(db, config, result.Signature)) {
+				failed++
+			} else {
+				infected++
+			}
+		}
+
+		job.SetProgress(db, int64(i+1))
+	}
+
+	LogError(job.Done(db, fmt.Sprintf("scanned %d files, %d infected, %d failed", len(files), infected, failed)))
+}
+
+//Scan a stored upload. Infected files are quarantined. Returns the
+//http status and message to respond with, or 0 if the file is clean
+func scanUpload(handlerData web.HandlerData, file *models.File) (int, string) {
+	result, err := scanner.ScanFile(handlerData.Config, file)
+	if err != nil {
+		log.Error("Can't scan file: ", err)
+		if handlerData.Config.Server.Scanner.AllowOnError {
+			return 0, ""
+		}
+
+		removeLocalFile(handlerData.Config, file.LocalName)
+		return http.StatusServiceUnavailable, "file can't be scanned for malware"
+	}
+
+	if result == nil || !result.Infected {
+		return 0, ""
+	}
+
+	log.Warnf("Upload '%s' of %s is infected: %s", file.Name, handlerData.User.Username, result.Signature)
+	if LogError(models.QuarantineLocalFile(handlerData.Config, file, handlerData.User, result.Signature)) {
+		removeLocalFile(handlerData.Config, file.LocalName)
+	}
+
+	return http.StatusUnprocessableEntity, "file is infected: " + result.Signature
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/DataManagerServer/scanner"
+)
+
+//Create handler data using a clamd which can't be reached and a stored upload
+func setupFailingScan(t *testing.T, allowOnError bool) (web.HandlerData, *models.File) {
+	dir, err := ioutil.TempDir("", "handlers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	// Get a free port which refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config := &models.Config{}
+	config.Server.PathConfig.FileStore = dir
+	config.Server.Scanner.Type = scanner.ClamdScanner
+	config.Server.Scanner.Address = address
+	config.Server.Scanner.Timeout = 10
+	config.Server.Scanner.AllowOnError = allowOnError
+
+	file := &models.File{Name: "upload.txt", LocalName: "upload"}
+	if err = ioutil.WriteFile(config.GetStorageFile(file.LocalName), []byte("hello world"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return web.HandlerData{
+		Config: config,
+		User:   &models.User{Username: "user"},
+	}, file
+}
+
+func TestScanUploadAllowOnError(t *testing.T) {
+	handlerData, file := setupFailingScan(t, true)
+
+	if status, message := scanUpload(handlerData, file); status != 0 {
+		t.Fatalf("expected upload to be accepted, got %d %s", status, message)
+	}
+
+	if _, err := os.Stat(handlerData.Config.GetStorageFile(file.LocalName)); err != nil {
+		t.Fatalf("upload was removed: %v", err)
+	}
+}
+
+func TestScanUploadDenyOnError(t *testing.T) {
+	handlerData, file := setupFailingScan(t, false)
+
+	if status, _ := scanUpload(handlerData, file); status != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, status)
+	}
+
+	if _, err := os.Stat(handlerData.Config.GetStorageFile(file.LocalName)); !os.IsNotExist(err) {
+		t.Fatalf("upload wasn't removed: %v", err)
+	}
+}
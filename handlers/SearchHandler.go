@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/JojiiOfficial/DataManagerServer/handlers/web"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/jinzhu/gorm"
+)
+
+//SearchHandler handler for full-text search in file contents
+func SearchHandler(handlerData web.HandlerData, w http.ResponseWriter, r *http.Request) {
+	if !handlerData.Config.Server.Search.Enabled {
+		sendResponse(w, models.ResponseError, "Search is disabled", nil, http.StatusNotImplemented)
+		return
+	}
+
+	var request models.SearchRequest
+	if !readRequestLimited(w, r, &request, handlerData.Config.Webserver.MaxRequestBodyLength) {
+		return
+	}
+
+	if len(strings.TrimSpace(request.Query)) == 0 {
+		sendResponse(w, models.ResponseError, "Bad request", nil, http.StatusBadRequest)
+		return
+	}
+
+	var namespace *models.Namespace
+	if !request.AllNamespaces {
+		// Select namespace
+		namespace = models.FindNamespace(handlerData.Db, request.Namespace, handlerData.User)
+
+		// Handle namespace errors (not found || no access)
+		if !handleNamespaceErorrs(namespace, handlerData.User, w) {
+			return
+		}
+	}
+
+	if request.Limit == 0 || request.Limit > models.MaxListLimit {
+		request.Limit = 50
+	}
+
+	results, err := models.SearchFiles(handlerData.Db, handlerData.Config, request.Query, request.Limit, func(db *gorm.DB) *gorm.DB {
+		if request.AllNamespaces {
+			return db.Where("namespaces.creator = ?", handlerData.User.ID)
+		}
+		return db.Where("files.namespace_id = ?", namespace.ID)
+	})
+	if LogError(err) {
+		sendServerError(w)
+		return
+	}
+
+	var response models.SearchResponse
+	for _, result := range results {
+		item := models.SearchResultItem{
+			FileResponseItem: models.FileResponseItem{
+				ID:           result.ID,
+				Name:         result.Name,
+				CreationDate: result.CreatedAt,
+				Size:         result.FileSize,
+				IsPublic:     result.IsPublic,
+				Expiry:       result.ExpiresAt,
+			},
+			Rank:    result.Rank,
+			Snippet: result.Snippet,
+		}
+
+		if result.PublicFilename.Valid {
+			item.PublicName = result.PublicFilename.String
+		}
+
+		response.Results = append(response.Results, item)
+	}
+
+	sendResponse(w, models.ResponseSuccess, "", response)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -10,10 +13,13 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/JojiiOfficial/DataManagerServer/download"
 	"github.com/JojiiOfficial/DataManagerServer/models"
 
 	"github.com/JojiiOfficial/gaw"
+	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -101,11 +107,34 @@ func hasEmptyValue(e reflect.Value) bool {
 	return false
 }
 
-func downloadHTTP(user *models.User, url string, f *os.File, file *models.File) (int, error) {
-	res, err := http.Get(url)
+//Download url into f. The progress is written into job if set. Canceling ctx stops the download
+func downloadHTTP(ctx context.Context, config *models.Config, db *gorm.DB, job *models.Job, user *models.User, url string, f io.Writer, file *models.File) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Check host before resolving it
+	if err = download.CheckURL(config, req.URL); err != nil {
+		return 0, err
+	}
+
+	// Only download changes of mirrored files
+	isRefresh := file.SourceURL == url
+	if isRefresh {
+		if len(file.ETag) > 0 {
+			req.Header.Set("If-None-Match", file.ETag)
+		}
+		if len(file.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", file.LastModified)
+		}
+	}
+
+	res, err := download.NewClient(config, user.Role).Do(req.WithContext(ctx))
 	if LogError(err) {
 		return 0, err
 	}
+	defer res.Body.Close()
 
 	//Don't read content on http error
 	if res.StatusCode < 200 || res.StatusCode > 299 {
@@ -127,17 +156,69 @@ func downloadHTTP(user *models.User, url string, f *os.File, file *models.File)
 		reader = res.Body
 	}
 
+	//Report progress. Total is 0 if the size is unknown
+	writer := f
+	if job != nil {
+		total := res.ContentLength
+		if total < 0 {
+			total = 0
+		}
+		if err = job.Start(db, total); LogError(err) {
+			return 0, err
+		}
+
+		writer = &progressWriter{Writer: f, db: db, job: job}
+	}
+
 	//Save body in file
-	size, err := io.Copy(f, reader)
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(writer, hash), reader)
 	if LogError(err) {
 		return 0, err
 	}
 
-	if err = res.Body.Close(); LogError(err) {
-		return 0, err
+	//Servers not supporting conditional requests send the same content again
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if isRefresh && checksum == file.Checksum {
+		return http.StatusNotModified, nil
 	}
 
-	//Set file size
+	//Set file size and cache headers
 	file.FileSize = size
+	file.Checksum = checksum
+	file.ETag = res.Header.Get("ETag")
+	file.LastModified = res.Header.Get("Last-Modified")
 	return res.StatusCode, nil
 }
+
+//Interval to write the progress of a download into its job
+const progressInterval = time.Second
+
+//Writer updating the progress of a job
+type progressWriter struct {
+	io.Writer
+	db         *gorm.DB
+	job        *models.Job
+	written    int64
+	lastUpdate time.Time
+}
+
+func (writer *progressWriter) Write(p []byte) (int, error) {
+	n, err := writer.Writer.Write(p)
+	writer.written += int64(n)
+
+	//Don't update the database on each write
+	if time.Since(writer.lastUpdate) >= progressInterval {
+		writer.lastUpdate = time.Now()
+		LogError(writer.job.SetProgress(writer.db, writer.written))
+	}
+
+	return n, err
+}
+
+//Remove a not yet saved file from the filestore
+func removeLocalFile(config *models.Config, localName string) {
+	if err := os.Remove(config.GetStorageFile(localName)); err != nil && !os.IsNotExist(err) {
+		LogError(err)
+	}
+}
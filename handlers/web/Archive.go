@@ -0,0 +1,325 @@
+package web
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	log "github.com/sirupsen/logrus"
+)
+
+//ArchiveFormat format of an archive
+type ArchiveFormat string
+
+//Supported archive formats
+const (
+	TarArchive   ArchiveFormat = "tar"
+	TarGzArchive ArchiveFormat = "tar.gz"
+	ZipArchive   ArchiveFormat = "zip"
+)
+
+//ErrUnsupportedArchive error if an archive format is not supported
+var ErrUnsupportedArchive = errors.New("unsupported archive format")
+
+//ParseArchiveFormat return the archive format from a string. Defaults to zip
+func ParseArchiveFormat(format string) (ArchiveFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "", "zip":
+		return ZipArchive, nil
+	case "tar":
+		return TarArchive, nil
+	case "tar.gz", "tgz", "targz":
+		return TarGzArchive, nil
+	}
+
+	return "", ErrUnsupportedArchive
+}
+
+//ContentType return the content type of the archive format
+func (format ArchiveFormat) ContentType() string {
+	switch format {
+	case TarArchive:
+		return "application/x-tar"
+	case TarGzArchive:
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+//Filename return filename with the extension of the archive format
+func (format ArchiveFormat) Filename(name string) string {
+	return name + "." + string(format)
+}
+
+//ArchiveWriter writes files into an archive stream
+type ArchiveWriter interface {
+	//WriteFile adds a file with the given name and size to the archive
+	WriteFile(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+//NewArchiveWriter create a new archivewriter writing into w
+func NewArchiveWriter(format ArchiveFormat, w io.Writer) (ArchiveWriter, error) {
+	switch format {
+	case ZipArchive:
+		return &zipArchiveWriter{
+			writer: zip.NewWriter(w),
+		}, nil
+	case TarArchive:
+		return &tarArchiveWriter{
+			writer: tar.NewWriter(w),
+		}, nil
+	case TarGzArchive:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{
+			writer: tar.NewWriter(gz),
+			gzip:   gz,
+		}, nil
+	}
+
+	return nil, ErrUnsupportedArchive
+}
+
+type zipArchiveWriter struct {
+	writer *zip.Writer
+}
+
+func (zw *zipArchiveWriter) WriteFile(name string, size int64, modTime time.Time, r io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+
+	w, err := zw.writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (zw *zipArchiveWriter) Close() error {
+	return zw.writer.Close()
+}
+
+type tarArchiveWriter struct {
+	writer *tar.Writer
+	gzip   *gzip.Writer
+}
+
+func (tw *tarArchiveWriter) WriteFile(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := tw.writer.WriteHeader(&tar.Header{
+		Name:     name,
+		Size:     size,
+		Mode:     0640,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tar needs exactly size bytes
+	_, err = io.CopyN(tw.writer, r, size)
+	return err
+}
+
+func (tw *tarArchiveWriter) Close() error {
+	if err := tw.writer.Close(); err != nil {
+		return err
+	}
+
+	if tw.gzip != nil {
+		return tw.gzip.Close()
+	}
+
+	return nil
+}
+
+//ArchiveNames creates unique entry names for an archive
+type ArchiveNames map[string]bool
+
+//Get return a unique and clean entry name for name
+func (names ArchiveNames) Get(name string) string {
+	// Don't allow paths in entry names
+	name = strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_").Replace(name))
+	if len(name) == 0 || name == "." || name == ".." {
+		name = "file"
+	}
+
+	unique := name
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; names[unique]; i++ {
+		unique = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	names[unique] = true
+	return unique
+}
+
+//ArchiveReader reads files from an archive stream
+type ArchiveReader interface {
+	//Next return the name and content of the next file in the archive. Returns io.EOF at the end
+	Next() (string, io.Reader, error)
+	Close() error
+}
+
+//NewArchiveReader create a new archivereader for a tar, tar.gz or zip archive.
+//Zip archives are buffered in a temporary file in tmpDir
+func NewArchiveReader(r io.Reader, tmpDir string) (ArchiveReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		// tar.gz
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchiveReader{
+			reader: tar.NewReader(gz),
+		}, nil
+	case bytes.Equal(magic, []byte("PK\x03\x04")):
+		// zip needs random access
+		tmpFile, err := ioutil.TempFile(tmpDir, "import_")
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := io.Copy(tmpFile, br)
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return nil, err
+		}
+
+		zr, err := zip.NewReader(tmpFile, size)
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+			return nil, err
+		}
+
+		return &zipArchiveReader{
+			reader:  zr,
+			tmpFile: tmpFile,
+		}, nil
+	}
+
+	return &tarArchiveReader{
+		reader: tar.NewReader(br),
+	}, nil
+}
+
+type tarArchiveReader struct {
+	reader *tar.Reader
+}
+
+func (tr *tarArchiveReader) Next() (string, io.Reader, error) {
+	for {
+		header, err := tr.reader.Next()
+		if err != nil {
+			return "", nil, err
+		}
+
+		// Only return regular files
+		if header.Typeflag == tar.TypeReg {
+			return header.Name, tr.reader, nil
+		}
+	}
+}
+
+func (tr *tarArchiveReader) Close() error {
+	return nil
+}
+
+type zipArchiveReader struct {
+	reader  *zip.Reader
+	tmpFile *os.File
+	pos     int
+	current io.ReadCloser
+}
+
+func (zr *zipArchiveReader) Next() (string, io.Reader, error) {
+	if zr.current != nil {
+		zr.current.Close()
+		zr.current = nil
+	}
+
+	for ; zr.pos < len(zr.reader.File); zr.pos++ {
+		file := zr.reader.File[zr.pos]
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return "", nil, err
+		}
+
+		zr.pos++
+		zr.current = rc
+		return file.Name, rc, nil
+	}
+
+	return "", nil, io.EOF
+}
+
+func (zr *zipArchiveReader) Close() error {
+	if zr.current != nil {
+		zr.current.Close()
+	}
+
+	zr.tmpFile.Close()
+	return os.Remove(zr.tmpFile.Name())
+}
+
+//ServeFilesArchive streams files as archive named name into w. Used for bulk downloads
+//and by CollectionArchiveHandler to download public collections
+func ServeFilesArchive(config *models.Config, w http.ResponseWriter, files []models.File, format ArchiveFormat, name string) error {
+	w.Header().Set(models.HeaderContentType, format.ContentType())
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+format.Filename(name)+"\"")
+
+	archive, err := NewArchiveWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	names := ArchiveNames{}
+	for i, file := range files {
+		f, err := filestore.Open(config, &files[i])
+		if err != nil {
+			// Skip files missing in filestore
+			log.Warn(err)
+			continue
+		}
+
+		err = archive.WriteFile(names.Get(file.Name), f.Size(), file.UpdatedAt, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+	"github.com/sbani/go-humanizer/units"
+)
+
+//GalleryFile template for collection galleries
+const GalleryFile = "Gallery.html"
+
+//Name of the cookie proving the password of a collection was entered
+const collectionCookie = "dm_collection"
+
+//CollectionPageHandler handler for the gallery of a public collection
+func CollectionPageHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
+	collection, ok := getPublicCollection(handlerData, w, r)
+	if !ok {
+		return
+	}
+
+	templateData := models.GalleryTemplate{
+		Title:      collection.PublicName,
+		PublicName: collection.PublicName,
+		Host:       r.Host,
+	}
+
+	// Check password
+	if collection.HasPassword() && !hasCollectionAccess(collection, r) {
+		if r.Method == http.MethodPost && collection.CheckPassword(r.PostFormValue("password")) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     collectionCookie,
+				Value:    collection.GetAccessToken(),
+				Path:     "/collection/" + collection.PublicName,
+				Expires:  getCookieExpiry(collection),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+			return
+		}
+
+		templateData.NeedPassword = true
+		templateData.WrongPassword = r.Method == http.MethodPost
+		LogError(serveGalleryTemplate(handlerData.Config, w, templateData))
+		return
+	}
+
+	files, err := collection.GetFiles(handlerData.Db, 0)
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	var totalSize int64
+	for _, file := range files {
+		totalSize += file.FileSize
+
+		templateData.Files = append(templateData.Files, models.GalleryFileItem{
+			ID:           file.ID,
+			Name:         file.Name,
+			FileSizeStr:  units.BinarySuffix(float64(file.FileSize)),
+			IsImage:      models.PreviewTypeFromMime(file.FileType) == models.ImagePreviewType,
+			HasThumbnail: file.HasThumbnail,
+			Encrypted:    file.IsEncrypted(),
+		})
+	}
+	templateData.TotalSizeStr = units.BinarySuffix(float64(totalSize))
+
+	LogError(serveGalleryTemplate(handlerData.Config, w, templateData))
+}
+
+//CollectionArchiveHandler streams all files of a public collection as archive
+func CollectionArchiveHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
+	collection, ok := getPublicCollection(handlerData, w, r)
+	if !ok || !checkCollectionAccess(collection, w, r) {
+		return
+	}
+
+	format, err := ParseArchiveFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	files, err := collection.GetFiles(handlerData.Db, 0)
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	LogError(ServeFilesArchive(handlerData.Config, w, files, format, collection.PublicName))
+}
+
+//CollectionFileHandler serves a file of a public collection. Use ?thumb=<size> to get its thumbnail
+func CollectionFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
+	collection, ok := getPublicCollection(handlerData, w, r)
+	if !ok || !checkCollectionAccess(collection, w, r) {
+		return
+	}
+
+	fileID, err := strconv.ParseUint(mux.Vars(r)["fileID"], 10, 32)
+	if err != nil {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+
+	files, err := collection.GetFiles(handlerData.Db, uint(fileID))
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	if len(files) == 0 {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+	file := files[0]
+
+	localFile := handlerData.Config.GetStorageFile(file.LocalName)
+	if size := r.URL.Query().Get("thumb"); len(size) > 0 && models.IsValidThumbnailSize(size) && file.HasThumbnail {
+		localFile = handlerData.Config.GetThumbnailFile(file.LocalName, size)
+	} else {
+		//Set content type of the file. Unsafe types are downloaded
+		setFileHeaders(w, &file)
+	}
+
+	f, err := filestore.OpenPath(&file, localFile)
+	if LogError(err) {
+		if os.IsNotExist(err) {
+			NotFoundHandler(handlerData, w, r)
+			return
+		}
+
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	http.ServeContent(w, r, file.Name, file.UpdatedAt, f)
+}
+
+//Get the collection of the request. Sends not found if it doesn't exist
+func getPublicCollection(handlerData HandlerData, w http.ResponseWriter, r *http.Request) (*models.Collection, bool) {
+	collection, found, err := models.GetPublicCollection(handlerData.Db, mux.Vars(r)["name"])
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !found {
+		NotFoundHandler(handlerData, w, r)
+		return nil, false
+	}
+
+	return collection, true
+}
+
+//Return true if the password isn't required or was entered
+func hasCollectionAccess(collection *models.Collection, r *http.Request) bool {
+	if !collection.HasPassword() {
+		return true
+	}
+
+	cookie, err := r.Cookie(collectionCookie)
+	return err == nil && cookie.Value == collection.GetAccessToken()
+}
+
+//Redirect to the password page if the password wasn't entered
+func checkCollectionAccess(collection *models.Collection, w http.ResponseWriter, r *http.Request) bool {
+	if hasCollectionAccess(collection, r) {
+		return true
+	}
+
+	http.Redirect(w, r, "/collection/"+collection.PublicName, http.StatusSeeOther)
+	return false
+}
+
+//Let the cookie expire with the collection
+func getCookieExpiry(collection *models.Collection) (expiry time.Time) {
+	if collection.ExpiresAt != nil {
+		expiry = *collection.ExpiresAt
+	}
+	return
+}
+
+func serveGalleryTemplate(config *models.Config, w http.ResponseWriter, data models.GalleryTemplate) error {
+	galleryFile := config.GetTemplateFile(GalleryFile)
+
+	t, err := template.New("").ParseFiles(galleryFile)
+	if err != nil {
+		return err
+	}
+
+	return t.ExecuteTemplate(w, path.Base(galleryFile), data)
+}
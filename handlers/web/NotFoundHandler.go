@@ -11,6 +11,9 @@ import (
 func NotFoundHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
 	log.Info("Not found: ", r.URL.Path)
 
+	setContentType(w, "text/html")
+	w.WriteHeader(http.StatusNotFound)
+
 	err := serveStaticFile(handlerData.Config, NotFoundFile, w)
 	if err != nil {
 		if os.IsNotExist(err) {
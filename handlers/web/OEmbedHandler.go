@@ -0,0 +1,144 @@
+package web
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+
+	// Register decoders to read image sizes
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+//oEmbedProvider name of this provider
+const oEmbedProvider = "DataManager"
+
+//OEmbedHandler returns oEmbed data of a public file
+func OEmbedHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	//Only json is supported
+	if format := query.Get("format"); len(format) > 0 && format != "json" {
+		http.Error(w, "Format not supported", http.StatusNotImplemented)
+		return
+	}
+
+	//Get public name from preview url
+	fileURL, err := url.Parse(query.Get("url"))
+	if err != nil || !strings.HasPrefix(fileURL.Path, "/preview/") {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+	publicName := path.Base(fileURL.Path)
+
+	//Get requested file
+	file, found, err := models.GetPublicFile(handlerData.Db, publicName)
+	if !found {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+
+	//Send error
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	//Send not found if not public or expired
+	if !file.IsPublic || file.IsExpired() {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+
+	baseURL := "https://" + r.Host
+	response := models.OEmbedResponse{
+		Version:      "1.0",
+		Type:         "link",
+		Title:        file.Name,
+		ProviderName: oEmbedProvider,
+		ProviderURL:  baseURL,
+	}
+
+	//Embed images as photo
+	encrypted := file.IsEncrypted()
+	if !encrypted && models.PreviewTypeFromMime(file.FileType) == models.ImagePreviewType {
+		maxWidth, _ := strconv.Atoi(query.Get("maxwidth"))
+		maxHeight, _ := strconv.Atoi(query.Get("maxheight"))
+
+		imageURL, width, height := oEmbedImage(handlerData.Config, file, baseURL, maxWidth, maxHeight)
+		if width > 0 && height > 0 {
+			response.Type = "photo"
+			response.URL = imageURL
+			response.Width = width
+			response.Height = height
+		}
+
+		//Add small thumbnail
+		if file.HasThumbnail {
+			response.ThumbnailURL = baseURL + "/preview/thumb/" + url.PathEscape(publicName) + "?size=" + models.DefaultThumbnailSize
+			response.ThumbnailWidth, response.ThumbnailHeight = imageSize(file, handlerData.Config.GetThumbnailFile(file.LocalName, models.DefaultThumbnailSize))
+		}
+	}
+
+	w.Header().Set(models.HeaderContentType, "application/json; charset=utf-8")
+	LogError(json.NewEncoder(w).Encode(response))
+}
+
+//Return the url and size of the largest image fitting into maxWidth and maxHeight (0 = unlimited)
+func oEmbedImage(config *models.Config, file *models.File, baseURL string, maxWidth, maxHeight int) (string, int, int) {
+	escapedName := url.PathEscape(file.PublicFilename.String)
+
+	// Try original image
+	width, height := imageSize(file, config.GetStorageFile(file.LocalName))
+	imageURL := baseURL + "/preview/raw/" + escapedName
+	if !file.HasThumbnail || fitsInto(width, height, maxWidth, maxHeight) {
+		return imageURL, width, height
+	}
+
+	// Use largest fitting thumbnail
+	var best int
+	for size, maxSize := range models.ThumbnailSizes {
+		if maxSize <= best {
+			continue
+		}
+
+		w, h := imageSize(file, config.GetThumbnailFile(file.LocalName, size))
+		if w > 0 && h > 0 && fitsInto(w, h, maxWidth, maxHeight) {
+			best = maxSize
+			imageURL = baseURL + "/preview/thumb/" + escapedName + "?size=" + size
+			width, height = w, h
+		}
+	}
+
+	return imageURL, width, height
+}
+
+func fitsInto(width, height, maxWidth, maxHeight int) bool {
+	return (maxWidth <= 0 || width <= maxWidth) && (maxHeight <= 0 || height <= maxHeight)
+}
+
+//Return the size of the image at path belonging to file or zero if it can't be read
+func imageSize(file *models.File, path string) (int, int) {
+	f, err := filestore.OpenPath(file, path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	imgConfig, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0
+	}
+
+	return imgConfig.Width, imgConfig.Height
+}
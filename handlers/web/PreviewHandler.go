@@ -6,7 +6,6 @@ import (
 	"strings"
 	"text/template"
 
-	"github.com/JojiiOfficial/DataManagerServer/constants"
 	"github.com/JojiiOfficial/DataManagerServer/models"
 	"github.com/gorilla/mux"
 	"github.com/sbani/go-humanizer/units"
@@ -23,8 +22,10 @@ const (
 
 //PrevievFileHandler handler for previews
 func PrevievFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
-	//Return raw file if useragent in the list of raw useragents
-	if handlerData.Config.IsRawUseragent(strings.ToLower(r.UserAgent())) {
+	//Return raw file if useragent in the list of raw useragents. Link preview
+	//crawlers need the preview page for its metadata
+	userAgent := strings.ToLower(r.UserAgent())
+	if !handlerData.Config.IsCrawlerUseragent(userAgent) && handlerData.Config.IsRawUseragent(userAgent) {
 		RawFileHandler(handlerData, w, r)
 		return
 	}
@@ -45,8 +46,8 @@ func PrevievFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.
 		return
 	}
 
-	//Send not found if not public
-	if !file.IsPublic {
+	//Send not found if not public or expired
+	if !file.IsPublic || file.IsExpired() {
 		NotFoundHandler(handlerData, w, r)
 		return
 	}
@@ -54,10 +55,24 @@ func PrevievFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.
 	templateData := models.PreviewTemplate{
 		Filename:       file.Name,
 		PublicFilename: file.PublicFilename.String,
-		PreviewType:    models.PreviewTypeFromMime(file.FileType),
+		PreviewType:    models.PreviewTypeFromFile(file.FileType, file.Name),
 		Host:           r.Host,
 		FileSizeStr:    units.BinarySuffix(float64(file.FileSize)),
-		Encrypted:      (file.Encryption.Valid && constants.EncryptionIValid(file.Encryption.Int32)),
+		Encrypted:      file.IsEncrypted(),
+		FileType:       file.FileType,
+	}
+
+	//Encrypted files can only be downloaded
+	if templateData.Encrypted {
+		templateData.PreviewType = models.DefaultPreviewType
+	}
+
+	//Render text files
+	if models.IsRenderedPreview(templateData.PreviewType) {
+		templateData.Content, templateData.Truncated, err = renderTextPreview(handlerData.Config, file, templateData.PreviewType)
+		if LogError(err) {
+			templateData.PreviewType = models.DefaultPreviewType
+		}
 	}
 
 	//Serve preview
@@ -73,9 +88,15 @@ func servePreviewTemplate(config *models.Config, w http.ResponseWriter, data int
 	//Create template
 	t := template.New("")
 	t.Funcs(template.FuncMap{
-		"IsImagePreview":   models.IsImagePreview,
-		"IsTextPreview":    models.IsTextPreview,
-		"IsDefaultPreview": models.IsDefaultPreview,
+		"IsImagePreview":    models.IsImagePreview,
+		"IsTextPreview":     models.IsTextPreview,
+		"IsCodePreview":     models.IsCodePreview,
+		"IsMarkdownPreview": models.IsMarkdownPreview,
+		"IsRenderedPreview": models.IsRenderedPreview,
+		"IsAudioPreview":    models.IsAudioPreview,
+		"IsVideoPreview":    models.IsVideoPreview,
+		"IsPDFPreview":      models.IsPDFPreview,
+		"IsDefaultPreview":  models.IsDefaultPreview,
 	})
 
 	t, err := t.ParseFiles(PreviewFile, ContentFile)
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+)
+
+func TestPreviewTemplateEscapesFilename(t *testing.T) {
+	config := &models.Config{}
+	config.Webserver.HTMLFiles = "../../html"
+
+	previewTypes := []models.PreviewType{
+		models.DefaultPreviewType,
+		models.TextPreviewType,
+		models.AudioPreviewType,
+		models.VideoPreviewType,
+		models.PDFPreviewType,
+	}
+
+	for _, previewType := range previewTypes {
+		w := httptest.NewRecorder()
+		err := servePreviewTemplate(config, w, models.PreviewTemplate{
+			Filename:       "<script>alert(1)</script>.mp3",
+			PublicFilename: "\"><script>alert(2)</script>",
+			PreviewType:    previewType,
+			Host:           "example.com",
+			FileType:       "audio/mpeg\"><script>alert(3)</script>",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body := w.Body.String(); strings.Contains(body, "<script>") {
+			t.Errorf("preview type %d contains unescaped user input", previewType)
+		}
+	}
+}
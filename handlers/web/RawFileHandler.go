@@ -4,15 +4,15 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
 	"github.com/JojiiOfficial/DataManagerServer/models"
 	"github.com/gorilla/mux"
-	"github.com/h2non/filetype"
 )
 
 //RawFileHandler handler for previews
 func RawFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	fileID  := vars["fileID"]
+	fileID := vars["fileID"]
 
 	//Get requested file
 	file, found, err := models.GetPublicFile(handlerData.Db, fileID)
@@ -27,19 +27,14 @@ func RawFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	//Send not found if not public
-	if !file.IsPublic {
+	//Send not found if not public or expired
+	if !file.IsPublic || file.IsExpired() {
 		NotFoundHandler(handlerData, w, r)
 		return
 	}
 
-	//Set content type header if available and valid
-	if len(file.FileType) > 0 && filetype.IsMIMESupported(file.FileType) {
-		setContentType(w, file.FileType)
-	}
-
 	//Open file
-	f, err := os.Open(handlerData.Config.GetStorageFile(file.LocalName))
+	f, err := filestore.Open(handlerData.Config, file)
 	if LogError(err) {
 		if os.IsNotExist(err) {
 			NotFoundHandler(handlerData, w, r)
@@ -50,5 +45,11 @@ func RawFileHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	serveFileStream(handlerData.Config, f, w)
+	defer f.Close()
+
+	//Set content type. Unsafe types are downloaded
+	setFileHeaders(w, file)
+
+	//Serve with support for range requests, used by audio, video and pdf previews
+	http.ServeContent(w, r, file.Name, file.UpdatedAt, f)
 }
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+)
+
+func TestPublicFileExpiry(t *testing.T) {
+	handlers := map[string]func(HandlerData, http.ResponseWriter, *http.Request){
+		"raw":     RawFileHandler,
+		"preview": PrevievFileHandler,
+		"thumb":   ThumbnailHandler,
+		"oembed":  OEmbedHandler,
+	}
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		status    int
+	}{
+		{"not expiring", nil, http.StatusOK},
+		{"not expired", &future, http.StatusOK},
+		{"expired", &past, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		handlerData := newTestHandlerData(t)
+		newTestPublicFile(t, handlerData, &models.File{
+			Name:      "file.txt",
+			LocalName: "file",
+			FileType:  "text/plain",
+			IsPublic:  true,
+			PublicFilename: sql.NullString{
+				String: "publicname",
+				Valid:  true,
+			},
+			ExpiresAt: test.expiresAt,
+		}, "content")
+
+		for name, handler := range handlers {
+			r := httptest.NewRequest(http.MethodGet, "/?url="+url.QueryEscape("https://example.com/preview/publicname"), nil)
+			r = mux.SetURLVars(r, map[string]string{"fileID": "publicname"})
+			w := httptest.NewRecorder()
+
+			handler(handlerData, w, r)
+			if w.Code != test.status {
+				t.Errorf("%s: %s responded %d, expected %d", test.name, name, w.Code, test.status)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+//Style used to highlight code
+const codeStyle = "github"
+
+//Markdown renderer. Raw HTML and dangerous links are omitted
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+//Render the content of a text file as HTML. Returns true if the
+//file is larger than the max preview size and was truncated
+func renderTextPreview(config *models.Config, file *models.File, previewType models.PreviewType) (string, bool, error) {
+	f, err := filestore.Open(config, file)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	// Read one more byte to know if the file is larger
+	maxSize := config.Webserver.MaxPreviewFilesize
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return "", false, err
+	}
+
+	truncated := int64(len(data)) > maxSize
+	if truncated {
+		data = data[:maxSize]
+	}
+	text := strings.ToValidUTF8(string(data), "")
+
+	var buff bytes.Buffer
+	switch previewType {
+	case models.MarkdownPreviewType:
+		err = markdown.Convert([]byte(text), &buff)
+	case models.CodePreviewType:
+		err = highlightCode(&buff, text, file.Name, file.FileType)
+	default:
+		buff.WriteString("<pre>" + html.EscapeString(text) + "</pre>")
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	return buff.String(), truncated, nil
+}
+
+//Write highlighted code. The language is detected by the filename, the mime or the code itself
+func highlightCode(w io.Writer, code, name, mime string) error {
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.MatchMimeType(mime)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+
+	formatter := chromahtml.New(chromahtml.WithLineNumbers(true), chromahtml.TabWidth(4))
+	return formatter.Format(w, styles.Get(codeStyle), iterator)
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+)
+
+//ThumbnailHandler handler for thumbnails of public images
+func ThumbnailHandler(handlerData HandlerData, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	fileID := vars["fileID"]
+
+	size := r.URL.Query().Get("size")
+	if len(size) == 0 {
+		size = models.DefaultThumbnailSize
+	}
+
+	if !models.IsValidThumbnailSize(size) {
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	//Get requested file
+	file, found, err := models.GetPublicFile(handlerData.Db, fileID)
+	if !found {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+
+	//Send error
+	if LogError(err) {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	//Send not found if not public or expired
+	if !file.IsPublic || file.IsExpired() {
+		NotFoundHandler(handlerData, w, r)
+		return
+	}
+
+	//Use original image until the thumbnail is created
+	if !file.HasThumbnail {
+		RawFileHandler(handlerData, w, r)
+		return
+	}
+
+	//Open thumbnail
+	f, err := filestore.OpenPath(file, handlerData.Config.GetThumbnailFile(file.LocalName, size))
+	if LogError(err) {
+		if os.IsNotExist(err) {
+			NotFoundHandler(handlerData, w, r)
+			return
+		}
+
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	//Thumbnails are recreated if the file gets replaced. Let clients revalidate them
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", fmt.Sprintf("\"%x-%s\"", file.UpdatedAt.UnixNano(), size))
+	http.ServeContent(w, r, "", file.UpdatedAt, f)
+}
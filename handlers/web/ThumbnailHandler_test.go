@@ -0,0 +1,69 @@
+package web
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/gorilla/mux"
+)
+
+func TestThumbnailRevalidatedAfterReplace(t *testing.T) {
+	handlerData := newTestHandlerData(t)
+	file := &models.File{
+		Name:         "image.png",
+		LocalName:    "image",
+		FileType:     "image/png",
+		IsPublic:     true,
+		HasThumbnail: true,
+		PublicFilename: sql.NullString{
+			String: "publicname",
+			Valid:  true,
+		},
+	}
+	newTestPublicFile(t, handlerData, file, "image")
+
+	err := ioutil.WriteFile(handlerData.Config.GetThumbnailFile(file.LocalName, "small"), []byte("thumbnail"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/?size=small", nil)
+		r = mux.SetURLVars(r, map[string]string{"fileID": "publicname"})
+		if len(etag) > 0 {
+			r.Header.Set("If-None-Match", etag)
+		}
+
+		w := httptest.NewRecorder()
+		ThumbnailHandler(handlerData, w, r)
+		return w
+	}
+
+	w := request("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) == 0 {
+		t.Fatalf("expected thumbnail with etag, got %d %q", w.Code, etag)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-cache" {
+		t.Errorf("thumbnails can be cached without revalidation: %s", cacheControl)
+	}
+
+	if w = request(etag); w.Code != http.StatusNotModified {
+		t.Errorf("expected unchanged thumbnail to be revalidated, got %d", w.Code)
+	}
+
+	// Replacing the content updates the file
+	err = handlerData.Db.Model(file).UpdateColumn("updated_at", file.UpdatedAt.Add(time.Second)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w = request(etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected new thumbnail after replace, got %d %s", w.Code, w.Header().Get("ETag"))
+	}
+}
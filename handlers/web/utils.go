@@ -6,9 +6,11 @@ import (
 	"mime"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/JojiiOfficial/DataManagerServer/models"
 	"github.com/JojiiOfficial/gaw"
+	"github.com/h2non/filetype"
 	"github.com/jinzhu/gorm"
 	log "github.com/sirupsen/logrus"
 )
@@ -49,6 +51,29 @@ func setContentType(w http.ResponseWriter, contentType string) {
 	w.Header().Set(models.HeaderContentType, fmt.Sprintf("%s; charset=utf-8", contentType))
 }
 
+//Set the headers for serving the content of a public file.
+//Files which can't be displayed safely are sent as attachment
+func setFileHeaders(w http.ResponseWriter, file *models.File) {
+	//Set content type header if available and valid
+	if len(file.FileType) > 0 && filetype.IsMIMESupported(file.FileType) {
+		if strings.HasPrefix(file.FileType, "text/") {
+			setContentType(w, file.FileType)
+		} else {
+			w.Header().Set(models.HeaderContentType, file.FileType)
+		}
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if !file.ServeInline() {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.Name})
+		if len(disposition) == 0 {
+			disposition = "attachment"
+		}
+		w.Header().Set("Content-Disposition", disposition)
+	}
+}
+
 //Serve static file
 func serveStaticFile(config *models.Config, file string, w http.ResponseWriter, contentType ...string) error {
 	//Open file
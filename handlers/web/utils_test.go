@@ -0,0 +1,78 @@
+package web
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+//Create handler data using a migrated in-memory database and a temporary filestore
+func newTestHandlerData(t *testing.T) HandlerData {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	err = db.AutoMigrate(
+		&models.Role{},
+		&models.Namespace{},
+		&models.Tag{},
+		&models.File{},
+		&models.Group{},
+		&models.User{},
+		&models.FileMeta{},
+	).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	config := &models.Config{}
+	config.Server.PathConfig.FileStore = dir
+	config.Webserver.HTMLFiles = "../../html"
+	config.Webserver.DownloadFileBuffer = 4096
+
+	return HandlerData{
+		Config: config,
+		Db:     db,
+	}
+}
+
+//Store a public file with content
+func newTestPublicFile(t *testing.T, handlerData HandlerData, file *models.File, content string) {
+	user := &models.User{Username: "user"}
+	if err := handlerData.Db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	namespace := &models.Namespace{Name: "user_test", UserID: user.ID}
+	if err := handlerData.Db.Create(namespace).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	file.Namespace = namespace
+	file.NamespaceID = namespace.ID
+	if err := file.Insert(handlerData.Db, user); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ioutil.WriteFile(path.Join(handlerData.Config.Server.PathConfig.FileStore, file.LocalName), []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/JojiiOfficial/gaw"
 
 	"github.com/JojiiOfficial/DataManagerServer/constants"
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
 	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/DataManagerServer/scanner"
 	"github.com/JojiiOfficial/DataManagerServer/storage"
 
 	"github.com/jinzhu/gorm"
@@ -39,11 +41,16 @@ var (
 	configCmd           = app.Command("config", "Commands for the config file")
 	configCmdCreate     = configCmd.Command("create", "Create config file")
 	configCmdCreateName = configCmdCreate.Arg("name", "Config filename").Default(models.GetDefaultConfig()).String()
+
+	//Key commands
+	//Key rotate
+	keyCmd       = app.Command("keys", "Commands for the master keys of encryption at rest")
+	keyCmdRotate = keyCmd.Command("rotate", "Wrap the keys of all files using the current master key. Old master keys have to be set in 'oldkeyfiles'")
 )
 
 var (
-	config  *models.Config
-	db      *gorm.DB
+	config *models.Config
+	db     *gorm.DB
 )
 
 //Env vars
@@ -105,6 +112,18 @@ func main() {
 			return
 		}
 
+		//Load master keys for encryption at rest
+		if err := filestore.LoadKeys(config); err != nil {
+			log.Fatalln("Can't load master keys:", err)
+			return
+		}
+
+		//Check malware scanner
+		if _, err := scanner.New(config); err != nil {
+			log.Fatalln("Invalid scanner:", err)
+			return
+		}
+
 		log.Debug("Connecting to db")
 
 		var err error
@@ -122,7 +141,6 @@ func main() {
 			return
 		}
 
-
 		log.Debug("Successfully connected to DB")
 	}
 
@@ -140,6 +158,17 @@ func main() {
 		{
 			models.InitConfig(*configCmdCreateName, true)
 		}
+	//Keys --------------------
+	case keyCmdRotate.FullCommand():
+		{
+			count, err := filestore.RotateKeys(config, db)
+			if err != nil {
+				log.Fatalln(err)
+				return
+			}
+
+			fmt.Printf("Rotated keys of %d files\n", count)
+		}
 	}
 }
 
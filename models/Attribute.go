@@ -0,0 +1,369 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//AttributeType type of a file attribute
+type AttributeType string
+
+//Attribute types
+const (
+	TagAttribute   AttributeType = "tag"
+	GroupAttribute AttributeType = "group"
+)
+
+//ErrAttributeExists error if a tag or group with the name already exists
+var ErrAttributeExists = errors.New("name already exists")
+
+//ErrTagIntoChild error if a tag would be moved into its own children
+var ErrTagIntoChild = errors.New("can't move a tag into its children")
+
+//ErrInvalidRenamePattern error if a replacement doesn't fit to its pattern
+var ErrInvalidRenamePattern = errors.New("replacement has more '*' than pattern")
+
+//Tables used by an attribute type
+type attributeTable struct {
+	Table      string
+	JoinTable  string
+	JoinColumn string
+	Index      string
+}
+
+//Tables of an attribute type
+var attributeTables = map[AttributeType]attributeTable{
+	TagAttribute:   {`tags`, `files_tags`, `tag_id`, `idx_tags_unique_name`},
+	GroupAttribute: {`"groups"`, `files_groups`, `group_id`, `idx_groups_unique_name`},
+}
+
+//AttributeUsage usage statistics of a tag or group
+type AttributeUsage struct {
+	Name      string
+	Namespace string
+	Files     int64
+	Size      int64
+	LastUsed  *time.Time
+}
+
+//IsValid return true if the attribute type exists
+func (attributeType AttributeType) IsValid() bool {
+	_, ok := attributeTables[attributeType]
+	return ok
+}
+
+//FindAttributeUsage lists tags or groups with their usage, optionally filtered by a name prefix.
+//Use filter to restrict the namespaces. Namespaces can be accessed using 'namespaces'
+func FindAttributeUsage(db *gorm.DB, attributeType AttributeType, prefix string, limit uint, filter func(*gorm.DB) *gorm.DB) ([]AttributeUsage, error) {
+	tables := attributeTables[attributeType]
+	table, joinTable, joinColumn := tables.Table, tables.JoinTable, tables.JoinColumn
+
+	query := db.Table(table).
+		Select(table + `.name AS name, namespaces.name AS namespace,
+			COUNT(DISTINCT files.id) AS files, COALESCE(SUM(files.file_size), 0) AS size, MAX(files.created_at) AS last_used`).
+		Joins("INNER JOIN namespaces ON namespaces.id = " + table + ".namespace_id AND namespaces.deleted_at IS NULL").
+		Joins("LEFT JOIN " + joinTable + " ON " + joinTable + "." + joinColumn + " = " + table + ".id").
+		Joins("LEFT JOIN files ON files.id = " + joinTable + ".file_id AND files.deleted_at IS NULL").
+		Where(table + ".deleted_at IS NULL")
+
+	if len(prefix) > 0 {
+		query = query.Where(table+".name LIKE ?", escapeLike(prefix)+"%")
+	}
+
+	query = filter(query).
+		Group(table + ".name, namespaces.name").
+		Order(table + ".name, namespaces.name")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var usage []AttributeUsage
+	err := query.Scan(&usage).Error
+	return usage, err
+}
+
+//AttributeRename a renamed tag or group
+type AttributeRename struct {
+	Name    string
+	NewName string
+	Merged  bool
+}
+
+//Find the ID of a tag or group of a user. Returns 0 if it doesn't exist
+func findAttributeID(db *gorm.DB, attributeType AttributeType, name string, namespaceID, userID uint) (uint, error) {
+	var row struct {
+		ID uint
+	}
+
+	err := db.Table(attributeTables[attributeType].Table).Select("id").
+		Where("name = ? AND namespace_id = ? AND user_id = ? AND deleted_at IS NULL", name, namespaceID, userID).
+		Scan(&row).Error
+
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, nil
+	}
+	return row.ID, err
+}
+
+//MergeAttributes moves all files of the source tags or groups to target and deletes the sources
+func MergeAttributes(db *gorm.DB, attributeType AttributeType, sources []uint, target uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return mergeAttributes(tx, attributeType, sources, target)
+	})
+}
+
+func mergeAttributes(tx *gorm.DB, attributeType AttributeType, sources []uint, target uint) error {
+	tables := attributeTables[attributeType]
+
+	// Repoint files. Files having source and target already are skipped
+	err := tx.Exec("INSERT INTO "+tables.JoinTable+" (file_id, "+tables.JoinColumn+") "+
+		"SELECT file_id, ? FROM "+tables.JoinTable+" WHERE "+tables.JoinColumn+" IN (?) ON CONFLICT DO NOTHING", target, sources).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec("DELETE FROM "+tables.JoinTable+" WHERE "+tables.JoinColumn+" IN (?)", sources).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Exec("UPDATE "+tables.Table+" SET deleted_at = NOW() WHERE id IN (?)", sources).Error
+}
+
+//RenameAttribute renames a tag or group. If the new name is already used, both are merged
+//if merge is true, otherwise ErrAttributeExists is returned. Returns true if merged
+func RenameAttribute(db *gorm.DB, attributeType AttributeType, id uint, newName string, namespaceID, userID uint, merge bool) (bool, error) {
+	var merged bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+
+		// Move children of nested tags too
+		if attributeType == TagAttribute {
+			newName = NormalizeTagName(newName)
+			if _, err = renameChildTags(tx, id, newName, namespaceID, userID, merge); err != nil {
+				return err
+			}
+		}
+
+		merged, err = renameAttribute(tx, attributeType, id, newName, namespaceID, userID, merge)
+		return err
+	})
+
+	return merged, err
+}
+
+//A renamed child tag
+type childTagRename struct {
+	ID uint
+	AttributeRename
+}
+
+//Rename all children of a tag to be children of newName. Returns the renamed children
+func renameChildTags(tx *gorm.DB, id uint, newName string, namespaceID, userID uint, merge bool) ([]childTagRename, error) {
+	var tag Tag
+	if err := tx.Select("name").Where("id = ?", id).First(&tag).Error; err != nil {
+		return nil, err
+	}
+
+	if tag.Name == newName {
+		return nil, nil
+	}
+
+	// A tag can't become its own child
+	if IsTagOrChild(newName, tag.Name) {
+		return nil, ErrTagIntoChild
+	}
+
+	var children []Tag
+	err := tx.Select("id, name").
+		Where("name LIKE ? AND namespace_id = ? AND user_id = ?", escapeLike(tag.Name)+TagSeparator+"%", namespaceID, userID).
+		Order("name").
+		Find(&children).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var renamed []childTagRename
+	for _, child := range children {
+		childName := newName + strings.TrimPrefix(child.Name, tag.Name)
+		merged, err := renameAttribute(tx, TagAttribute, child.ID, childName, namespaceID, userID, merge)
+		if err != nil {
+			return nil, err
+		}
+
+		renamed = append(renamed, childTagRename{
+			ID: child.ID,
+			AttributeRename: AttributeRename{
+				Name:    child.Name,
+				NewName: childName,
+				Merged:  merged,
+			},
+		})
+	}
+
+	return renamed, nil
+}
+
+func renameAttribute(tx *gorm.DB, attributeType AttributeType, id uint, newName string, namespaceID, userID uint, merge bool) (bool, error) {
+	target, err := findAttributeID(tx, attributeType, newName, namespaceID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	// Nothing to do
+	if target == id {
+		return false, nil
+	}
+
+	if target != 0 {
+		if !merge {
+			return false, ErrAttributeExists
+		}
+
+		return true, mergeAttributes(tx, attributeType, []uint{id}, target)
+	}
+
+	return false, tx.Exec("UPDATE "+attributeTables[attributeType].Table+" SET name = ?, updated_at = NOW() WHERE id = ?", newName, id).Error
+}
+
+//BulkRenameAttributes renames all tags or groups of a user matching pattern. Each '*' in
+//pattern matches any text, which replaces the '*' in replacement at the same position
+func BulkRenameAttributes(db *gorm.DB, attributeType AttributeType, pattern, replacement string, namespaceID, userID uint, merge bool) ([]AttributeRename, error) {
+	if strings.Count(replacement, "*") > strings.Count(pattern, "*") {
+		return nil, ErrInvalidRenamePattern
+	}
+
+	// Build regex to extract the wildcard parts
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	patternRegex := regexp.MustCompile("^" + strings.Join(parts, "(.*)") + "$")
+	replacementParts := strings.Split(replacement, "*")
+
+	var renamed []AttributeRename
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID   uint
+			Name string
+		}
+
+		err := tx.Table(attributeTables[attributeType].Table).Select("id, name").
+			Where("name LIKE ? AND namespace_id = ? AND user_id = ? AND deleted_at IS NULL", globToLike(pattern), namespaceID, userID).
+			Order("name").
+			Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		// Children of renamed tags are renamed with their parent
+		renamedChildren := make(map[uint]bool)
+
+		for _, row := range rows {
+			if renamedChildren[row.ID] {
+				continue
+			}
+
+			match := patternRegex.FindStringSubmatch(row.Name)
+			if match == nil {
+				continue
+			}
+
+			// Build new name
+			var newName strings.Builder
+			for i, part := range replacementParts {
+				if i > 0 {
+					newName.WriteString(match[i])
+				}
+				newName.WriteString(part)
+			}
+
+			name := newName.String()
+			if attributeType == TagAttribute {
+				name = NormalizeTagName(name)
+			}
+
+			if len(name) == 0 || name == row.Name {
+				continue
+			}
+
+			var children []childTagRename
+			if attributeType == TagAttribute {
+				if children, err = renameChildTags(tx, row.ID, name, namespaceID, userID, merge); err != nil {
+					return err
+				}
+			}
+
+			merged, err := renameAttribute(tx, attributeType, row.ID, name, namespaceID, userID, merge)
+			if err != nil {
+				return err
+			}
+
+			renamed = append(renamed, AttributeRename{
+				Name:    row.Name,
+				NewName: name,
+				Merged:  merged,
+			})
+
+			for _, child := range children {
+				renamedChildren[child.ID] = true
+				renamed = append(renamed, child.AttributeRename)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return renamed, nil
+}
+
+//CreateAttributeIndexes merges duplicate tags and groups and makes
+//their names unique per namespace and user
+func CreateAttributeIndexes(db *gorm.DB) error {
+	for _, tables := range attributeTables {
+		// Map duplicates to the oldest attribute with the same name
+		duplicates := `SELECT id FROM (SELECT id, MIN(id) OVER (PARTITION BY name, namespace_id, user_id) AS keep
+			FROM ` + tables.Table + ` WHERE deleted_at IS NULL) d WHERE id <> keep`
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`INSERT INTO ` + tables.JoinTable + ` (file_id, ` + tables.JoinColumn + `)
+				SELECT j.file_id, d.keep FROM ` + tables.JoinTable + ` j
+				INNER JOIN (SELECT id, MIN(id) OVER (PARTITION BY name, namespace_id, user_id) AS keep
+					FROM ` + tables.Table + ` WHERE deleted_at IS NULL) d ON d.id = j.` + tables.JoinColumn + `
+				WHERE d.id <> d.keep
+				ON CONFLICT DO NOTHING`).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec("DELETE FROM " + tables.JoinTable + " WHERE " + tables.JoinColumn + " IN (" + duplicates + ")").Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec("UPDATE " + tables.Table + " SET deleted_at = NOW() WHERE id IN (" + duplicates + ")").Error
+			if err != nil {
+				return err
+			}
+
+			// Deleted attributes can be recreated
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + tables.Index + " ON " + tables.Table +
+				" (name, namespace_id, user_id) WHERE deleted_at IS NULL").Error
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBulkRenameNestedTags(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	for _, name := range []string{"photos", "photos/2020", "photos/2020/summer", "photoshop", "videos"} {
+		GetTag(db, name, namespace, user)
+	}
+
+	renamed, err := BulkRenameAttributes(db, TagAttribute, "photo*", "image*", namespace.ID, user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []AttributeRename{
+		{Name: "photos", NewName: "images"},
+		{Name: "photos/2020", NewName: "images/2020"},
+		{Name: "photos/2020/summer", NewName: "images/2020/summer"},
+		{Name: "photoshop", NewName: "imageshop"},
+	}
+	if !reflect.DeepEqual(renamed, expected) {
+		t.Errorf("expected renames %v, got %v", expected, renamed)
+	}
+
+	var names []string
+	err = db.Model(&Tag{}).Where("namespace_id = ?", namespace.ID).Order("name").Pluck("name", &names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedNames := []string{"images", "images/2020", "images/2020/summer", "imageshop", "videos"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("expected tags %v, got %v", expectedNames, names)
+	}
+}
+
+func TestBulkRenameMovesChildrenOfExactMatch(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	for _, name := range []string{"photos", "photos/2020"} {
+		GetTag(db, name, namespace, user)
+	}
+
+	// The pattern matches the parent only
+	if _, err := BulkRenameAttributes(db, TagAttribute, "photos", "images", namespace.ID, user.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	err := db.Model(&Tag{}).Where("namespace_id = ?", namespace.ID).Order("name").Pluck("name", &names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"images", "images/2020"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected tags %v, got %v", expected, names)
+	}
+}
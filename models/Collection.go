@@ -0,0 +1,197 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/JojiiOfficial/gaw"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//CollectionType type of a public collection
+type CollectionType uint8
+
+//Collection types
+const (
+	NamespaceCollection CollectionType = iota
+	GroupCollection
+	TagCollection
+)
+
+//CollectionTypeNames names of the collection types
+var CollectionTypeNames = map[CollectionType]string{
+	NamespaceCollection: "namespace",
+	GroupCollection:     "group",
+	TagCollection:       "tag",
+}
+
+//ErrInvalidCollectionType error if a collection type doesn't exist
+var ErrInvalidCollectionType = errors.New("invalid collection type")
+
+//Collection a namespace, group or tag published as gallery
+type Collection struct {
+	gorm.Model
+	PublicName   string         `gorm:"unique;not null"`
+	Type         CollectionType `gorm:"type:smallint"`
+	Name         string
+	NamespaceID  uint       `sql:"index" gorm:"not null"`
+	Namespace    *Namespace `gorm:"association_autoupdate:false;association_autocreate:false"`
+	UserID       uint       `sql:"index" gorm:"not null"`
+	User         *User      `gorm:"association_autoupdate:false;association_autocreate:false"`
+	ExpiresAt    *time.Time `sql:"index"`
+	PasswordHash string
+}
+
+//ParseCollectionType return the collection type by its name
+func ParseCollectionType(name string) (CollectionType, error) {
+	for collectionType, typeName := range CollectionTypeNames {
+		if typeName == name {
+			return collectionType, nil
+		}
+	}
+
+	return 0, ErrInvalidCollectionType
+}
+
+//Publish inserts the collection using publicName or a random name. Returns true if the name is already taken
+func (collection *Collection) Publish(db *gorm.DB, publicName string) (bool, error) {
+	if len(publicName) == 0 {
+		publicName = gaw.RandString(25)
+	}
+	collection.PublicName = publicName
+
+	//Check if public name already exists
+	var count uint
+	if err := db.Model(&Collection{}).Where("public_name = ?", publicName).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	return false, db.Create(collection).Error
+}
+
+//SetPassword sets or removes (empty password) the password of the collection
+func (collection *Collection) SetPassword(password string) error {
+	if len(password) == 0 {
+		collection.PasswordHash = ""
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	collection.PasswordHash = string(hash)
+	return nil
+}
+
+//HasPassword return true if the collection is protected by a password
+func (collection Collection) HasPassword() bool {
+	return len(collection.PasswordHash) > 0
+}
+
+//CheckPassword return true if password is the password of the collection
+func (collection Collection) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(collection.PasswordHash), []byte(password)) == nil
+}
+
+//GetAccessToken return a token proving the password was entered. Changes with the password
+func (collection Collection) GetAccessToken() string {
+	return gaw.SHA512(collection.PublicName + collection.PasswordHash)
+}
+
+//IsExpired return true if the collection is expired
+func (collection Collection) IsExpired() bool {
+	return collection.ExpiresAt != nil && collection.ExpiresAt.Before(time.Now())
+}
+
+//GetPublicCollection returns a collection by its public name if it's not expired
+func GetPublicCollection(db *gorm.DB, publicName string) (*Collection, bool, error) {
+	var collection Collection
+	err := db.Where("public_name = ?", publicName).Preload("Namespace").First(&collection).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if collection.IsExpired() || collection.Namespace == nil {
+		return nil, false, nil
+	}
+
+	return &collection, true, nil
+}
+
+//FindUserCollections returns all collections of a user
+func FindUserCollections(db *gorm.DB, user *User) ([]Collection, error) {
+	var collections []Collection
+	err := db.Where("user_id = ?", user.ID).Preload("Namespace").Order("id").Find(&collections).Error
+	return collections, err
+}
+
+//GetFiles returns all files of the collection. Use fileID to get a single file
+func (collection Collection) GetFiles(db *gorm.DB, fileID uint) ([]File, error) {
+	query := db.Model(&File{}).
+		Where("files.namespace_id = ? AND files.quarantined = false", collection.NamespaceID).
+		Where("files.expires_at IS NULL OR files.expires_at > ?", time.Now())
+
+	switch collection.Type {
+	case GroupCollection:
+		query = FilterFilesByGroups(query, []string{collection.Name})
+	case TagCollection:
+		query = FilterFilesByTags(query, []string{collection.Name})
+	}
+
+	if fileID > 0 {
+		query = query.Where("files.id = ?", fileID)
+	}
+
+	var files []File
+	err := query.Order("files.created_at DESC").Preload("Namespace").Preload("User.Role").Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Only show files the uploader is allowed to publish
+	published := files[:0]
+	for _, file := range files {
+		if file.CanPublish(file.User) {
+			published = append(published, file)
+		}
+	}
+
+	return published, nil
+}
+
+//ToResponse returns a response item for the collection
+func (collection Collection) ToResponse() CollectionResponseItem {
+	item := CollectionResponseItem{
+		PublicName: collection.PublicName,
+		Type:       CollectionTypeNames[collection.Type],
+		Name:       collection.Name,
+		Expiry:     collection.ExpiresAt,
+		Protected:  collection.HasPassword(),
+	}
+
+	if collection.Namespace != nil {
+		item.Namespace = collection.Namespace.Name
+	}
+
+	return item
+}
+
+//Delete deletes the collection. Its public name can be used again
+func (collection *Collection) Delete(db *gorm.DB) error {
+	return db.Unscoped().Delete(collection).Error
+}
+
+//DeleteExpiredCollections deletes all expired collections
+func DeleteExpiredCollections(db *gorm.DB) (int64, error) {
+	result := db.Unscoped().Where("expires_at < ?", time.Now()).Delete(&Collection{})
+	return result.RowsAffected, result.Error
+}
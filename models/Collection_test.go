@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectionGetFilesSkipsExpired(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	files := []*File{
+		{Name: "active.txt", LocalName: "active", ExpiresAt: &future},
+		{Name: "forever.txt", LocalName: "forever"},
+		{Name: "expired.txt", LocalName: "expired", ExpiresAt: &past},
+	}
+	for _, file := range files {
+		file.Namespace = namespace
+		file.NamespaceID = namespace.ID
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collection := Collection{Type: NamespaceCollection, NamespaceID: namespace.ID}
+	found, err := collection.GetFiles(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(found))
+	}
+	for _, file := range found {
+		if file.Name == "expired.txt" {
+			t.Error("expired file is part of the collection")
+		}
+	}
+
+	if found, err = collection.GetFiles(db, files[2].ID); err != nil || len(found) != 0 {
+		t.Errorf("expired file found by id: %v %v", found, err)
+	}
+}
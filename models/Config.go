@@ -35,16 +35,59 @@ type configServer struct {
 	Database          configDBstruct
 	PathConfig        pathConfig
 	Roles             roleConfig
+	Search            searchConfig
+	Thumbnails        thumbnailConfig
+	URLDownloads      urlDownloadConfig
+	EncryptionAtRest  encryptionAtRestConfig
+	Scanner           scannerConfig
 	AllowRegistration bool `default:"false"`
 }
 
+type searchConfig struct {
+	Enabled      bool
+	Language     string `default:"simple"`
+	MaxIndexSize int64  `default:"10000000"`
+}
+
+type thumbnailConfig struct {
+	Enabled   bool
+	MaxPixels int64 `default:"100000000"`
+}
+
+type urlDownloadConfig struct {
+	AllowPrivateNetworks bool
+	AllowedNetworks      []string
+	AllowedHosts         []string
+	DeniedHosts          []string
+	MaxRedirects         int `default:"5"`
+	ConnectTimeout       int `default:"10"`
+	Timeout              int `default:"3600"`
+}
+
+type encryptionAtRestConfig struct {
+	Enabled       bool
+	Cipher        string `default:"aes-256-gcm"`
+	MasterKey     string
+	MasterKeyFile string
+	OldKeyFiles   []string
+}
+
+type scannerConfig struct {
+	Type         string
+	Address      string
+	Command      []string
+	Timeout      int `default:"300"`
+	AllowOnError bool
+}
+
 type roleConfig struct {
 	DefaultRole uint `required:"true"`
 	Roles       []Role
 }
 
 type pathConfig struct {
-	FileStore string `required:"true"`
+	FileStore  string `required:"true"`
+	Quarantine string `default:"./quarantine"`
 }
 
 type configDBstruct struct {
@@ -116,7 +159,28 @@ func InitConfig(confFile string, createMode bool) (*Config, bool) {
 					SSLMode:      "require",
 				},
 				PathConfig: pathConfig{
-					FileStore: "./files",
+					FileStore:  "./files",
+					Quarantine: "./quarantine",
+				},
+				Search: searchConfig{
+					Enabled:      true,
+					Language:     "simple",
+					MaxIndexSize: 10000000,
+				},
+				Thumbnails: thumbnailConfig{
+					Enabled:   true,
+					MaxPixels: 100000000,
+				},
+				URLDownloads: urlDownloadConfig{
+					MaxRedirects:   5,
+					ConnectTimeout: 10,
+					Timeout:        3600,
+				},
+				EncryptionAtRest: encryptionAtRestConfig{
+					Cipher: "aes-256-gcm",
+				},
+				Scanner: scannerConfig{
+					Timeout: 300,
 				},
 				AllowRegistration: false,
 				Roles: roleConfig{
@@ -229,6 +293,16 @@ func (config *Config) Check() bool {
 		log.Infof("Filestorage path '%s' created", config.Server.PathConfig.FileStore)
 	}
 
+	//Check quarantine dir if files are scanned
+	if len(config.Server.Scanner.Type) > 0 && !DirExists(config.Server.PathConfig.Quarantine) {
+		err := os.Mkdir(config.Server.PathConfig.Quarantine, 0700)
+		if err != nil {
+			log.Fatal(err)
+			return false
+		}
+		log.Infof("Quarantine path '%s' created", config.Server.PathConfig.Quarantine)
+	}
+
 	//Check default role
 	if config.GetDefaultRole() == nil {
 		log.Fatalln("Can't find default role. You need to specify the ID of the role to use as default")
@@ -246,6 +320,28 @@ func (config *Config) Check() bool {
 	return true
 }
 
+//CrawlerUserAgents useragents of link preview crawlers. They get the preview
+//page for its metadata, even if they are in the list of raw useragents
+var CrawlerUserAgents = []string{
+	"telegrambot",
+	"slackbot",
+	"slack-imgproxy",
+	"discordbot",
+	"twitterbot",
+	"facebookexternalhit",
+	"whatsapp",
+	"linkedinbot",
+	"skypeuripreview",
+	"mattermost",
+	"embedly",
+}
+
+//IsCrawlerUseragent return true if agent is a link preview crawler
+func (config Config) IsCrawlerUseragent(agent string) bool {
+	agent = strings.ToLower(agent)
+	return gaw.IsInStringArrayContains(agent, CrawlerUserAgents)
+}
+
 //IsRawUseragent return true if file should be raw depending on useragent
 func (config Config) IsRawUseragent(agent string) bool {
 	agent = strings.ToLower(agent)
@@ -257,6 +353,11 @@ func (config Config) GetStorageFile(fileName string) string {
 	return path.Join(config.Server.PathConfig.FileStore, fileName)
 }
 
+//GetQuarantineFile return the path of a quarantined file
+func (config Config) GetQuarantineFile(fileName string) string {
+	return path.Join(config.Server.PathConfig.Quarantine, fileName)
+}
+
 //GetHTMLFile return path of html file
 func (config Config) GetHTMLFile(fileName string) string {
 	return path.Join(config.Webserver.HTMLFiles, fileName)
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+//ExportManifestName name of the manifest in an exported archive
+const ExportManifestName = "manifest.json"
+
+//ExportManifestVersion version of the manifest format
+const ExportManifestVersion = 1
+
+//ExportManifest manifest describing the files of an exported archive
+type ExportManifest struct {
+	Version   int                  `json:"version"`
+	Namespace string               `json:"ns"`
+	Created   time.Time            `json:"created"`
+	Files     []ExportManifestFile `json:"files"`
+}
+
+//ExportManifestFile a file in an exported archive
+type ExportManifestFile struct {
+	Path       string            `json:"path"`
+	Name       string            `json:"name"`
+	Size       int64             `json:"size"`
+	Type       string            `json:"type,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Groups     []string          `json:"groups,omitempty"`
+	Encryption string            `json:"e,omitempty"`
+	EncParams  map[string]string `json:"ep,omitempty"`
+	IsPublic   bool              `json:"isPub"`
+	PublicName string            `json:"pubname,omitempty"`
+	Expiry     *time.Time        `json:"expiry,omitempty"`
+	Created    time.Time         `json:"created"`
+	Updated    time.Time         `json:"updated"`
+
+	Description string                 `json:"desc,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+}
+
+//ToManifestFile returns the manifest entry for the file stored as path
+func (file File) ToManifestFile(path string) ExportManifestFile {
+	item := ExportManifestFile{
+		Path:     path,
+		Name:     file.Name,
+		Size:     file.FileSize,
+		Type:     file.FileType,
+		Tags:     TagArrToStringArr(file.Tags),
+		Groups:   GroupArrToStringArr(file.Groups),
+		IsPublic: file.IsPublic,
+		Expiry:   file.ExpiresAt,
+		Created:  file.CreatedAt,
+		Updated:  file.UpdatedAt,
+
+		Description: file.Description,
+		Meta:        MetaToMap(file.Meta),
+	}
+
+	item.Encryption, item.EncParams = file.GetEncryption()
+
+	if file.PublicFilename.Valid {
+		item.PublicName = file.PublicFilename.String
+	}
+
+	return item
+}
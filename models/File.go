@@ -2,6 +2,8 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -30,22 +32,49 @@ type File struct {
 	Tags           []Tag          `gorm:"many2many:files_tags;association_autoupdate:false"`
 	Namespace      *Namespace     `gorm:"association_autoupdate:false;association_autocreate:false;"`
 	NamespaceID    uint           `sql:"index" gorm:"not null"`
-	Encryption     sql.NullInt32
+	Encryption     sql.NullString
+	ExpiresAt      *time.Time `sql:"index"`
+	Description    string     `gorm:"type:text"`
+	Meta           []FileMeta `gorm:"association_autoupdate:false;association_autocreate:false"`
+	HasThumbnail   bool       `gorm:"default:false"`
+
+	// Parameters clients need to decrypt the file, eg. the nonce. Stored as json
+	EncryptionParams string `gorm:"type:text"`
+
+	// Mirrored files are refreshed from their source
+	SourceURL       string
+	RefreshInterval time.Duration
+	NextRefresh     *time.Time `sql:"index"`
+	ETag            string
+	LastModified    string
+	Checksum        string
+
+	// Infected files are quarantined
+	Quarantined bool `gorm:"default:false"`
+	Signature   string
+
+	// Data key of files encrypted at rest, wrapped by a master key
+	DataKey   []byte
+	DataKeyID string
 }
 
 //FileAttributes attributes for a file
 type FileAttributes struct {
-	Tags      []string `json:"tags,omitempty"`
-	Groups    []string `json:"groups,omitempty"`
-	Namespace string   `json:"ns"`
+	Tags        []string               `json:"tags,omitempty"`
+	Groups      []string               `json:"groups,omitempty"`
+	Namespace   string                 `json:"ns"`
+	Description string                 `json:"desc,omitempty"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
 }
 
 //GetAttributes get file attributes
 func (file File) GetAttributes() FileAttributes {
 	return FileAttributes{
-		Groups:    GroupArrToStringArr(file.Groups),
-		Tags:      TagArrToStringArr(file.Tags),
-		Namespace: file.GetNamespace().Name,
+		Groups:      GroupArrToStringArr(file.Groups),
+		Tags:        TagArrToStringArr(file.Tags),
+		Namespace:   file.GetNamespace().Name,
+		Description: file.Description,
+		Meta:        MetaToMap(file.Meta),
 	}
 }
 
@@ -93,11 +122,11 @@ func (file File) GetNamespace() *Namespace {
 	return file.Namespace
 }
 
-//IsInTagList return true if file has one of the specified tags
+//IsInTagList return true if file has one of the specified tags or one of their children
 func (file File) IsInTagList(tags []string) bool {
 	for _, tag := range file.Tags {
 		for _, t1 := range tags {
-			if tag.Name == t1 {
+			if IsTagOrChild(tag.Name, t1) {
 				return true
 			}
 		}
@@ -117,24 +146,26 @@ func (file File) IsInGroupList(groups []string) bool {
 	return false
 }
 
-//FindFiles finds file
-func FindFiles(db *gorm.DB, file File) ([]File, error) {
+//FindFiles finds file. query is optional. Files have to have one of the tags and be in one of the groups
+func FindFiles(db *gorm.DB, file File, query *FileQuery, tags, groups []string) ([]File, error) {
 	var files []File
-	a := db.Model(&File{})
+	a := query.Apply(db.Model(&File{}))
+	a = FilterFilesByTags(a, tags)
+	a = FilterFilesByGroups(a, groups)
 
 	// Filter by filename
 	if len(file.Name) > 0 {
-		a = a.Where("name like ?", file.Name)
+		a = a.Where("files.name like ?", file.Name)
 	}
 
 	// Filter by ID
 	if file.ID != 0 {
-		a = a.Where("id = ?", file.ID)
+		a = a.Where("files.id = ?", file.ID)
 	}
 
 	// Filter by namespace ID and uploader
 	if file.Namespace != nil {
-		a = a.Where("namespace_id = ? AND uploader = ?", file.Namespace.ID, file.Namespace.User.ID)
+		a = a.Where("files.namespace_id = ? AND files.uploader = ?", file.Namespace.ID, file.Namespace.User.ID)
 	}
 
 	//Get file to delete
@@ -143,6 +174,7 @@ func FindFiles(db *gorm.DB, file File) ([]File, error) {
 		Preload("Namespace.User").
 		Preload("Tags").
 		Preload("Groups").
+		Preload("Meta").
 		Find(&files).Error
 	if err != nil {
 		return nil, err
@@ -204,36 +236,60 @@ func (file *File) Delete(db *gorm.DB, config *Config) error {
 		return err
 	}
 
-	localFile := config.GetStorageFile(file.LocalName)
-	s, err := os.Stat(localFile)
-	if err != nil {
-		log.Warn(err)
+	// Shredder file in background
+	if file.Quarantined {
+		go ShredQuarantinedFile(config, file.LocalName)
 	} else {
-		// Shredder file in background
-		go (func() {
-			var shredConfig *shred.ShredderConf
-
-			if s.Size() >= 1000000000 {
-				// Size >= 1GB
-				shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros, 1, true)
-			} else if s.Size() >= 10000000 {
-				// Size >= 10MB
-				shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros|shred.WriteRand, 2, true)
-			} else {
-				shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros|shred.WriteRandSecure, 3, true)
-			}
+		go ShredLocalFile(config, file.LocalName)
+	}
 
-			// Delete local file
-			start := time.Now()
-			shredConfig.ShredFile(localFile)
-			log.Debug("Shredding took ", time.Since(start).String())
-		})()
+	// Remove from search index
+	if err = DeleteFileContent(db, file.ID); err != nil {
+		return err
+	}
+
+	// Remove metadata
+	if err = db.Delete(&FileMeta{}, "file_id = ?", file.ID).Error; err != nil {
+		return err
 	}
 
 	// Delete from DB
 	return db.Delete(&file).Error
 }
 
+//ShredLocalFile shreds and removes a file from the filestore
+func ShredLocalFile(config *Config, localName string) {
+	RemoveThumbnails(config, localName)
+
+	shredFile(config.GetStorageFile(localName))
+}
+
+//Shred and remove a file
+func shredFile(localFile string) {
+	s, err := os.Stat(localFile)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+
+	var shredConfig *shred.ShredderConf
+
+	if s.Size() >= 1000000000 {
+		// Size >= 1GB
+		shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros, 1, true)
+	} else if s.Size() >= 10000000 {
+		// Size >= 10MB
+		shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros|shred.WriteRand, 2, true)
+	} else {
+		shredConfig = shred.NewShredderConf(&shredder, shred.WriteZeros|shred.WriteRandSecure, 3, true)
+	}
+
+	// Delete local file
+	start := time.Now()
+	shredConfig.ShredFile(localFile)
+	log.Debug("Shredding took ", time.Since(start).String())
+}
+
 // Rename renames a file
 func (file *File) Rename(db *gorm.DB, newName string) error {
 	file.Name = newName
@@ -362,7 +418,7 @@ func (file File) GetCount(db *gorm.DB, fileID uint) (uint, error) {
 //GetPublicFile returns a file which is public
 func GetPublicFile(db *gorm.DB, publicFilename string) (*File, bool, error) {
 	var file File
-	err := db.Model(&File{}).Where("public_filename = ?", publicFilename).First(&file).Error
+	err := db.Model(&File{}).Where("public_filename = ?", publicFilename).Preload("Namespace").Preload("User.Role").First(&file).Error
 	if err != nil {
 		//Check error. Send server error if error is not "not found"
 		if gorm.IsRecordNotFoundError(err) {
@@ -434,20 +490,116 @@ func (file *File) Publish(db *gorm.DB, publicName string) (bool, error) {
 	return false, file.Save(db)
 }
 
-//SetEncryption set encryption
-func (file *File) SetEncryption(encription string) *File {
-	e := sql.NullInt32{
+//SetEncryption set encryption cipher and its parameters
+func (file *File) SetEncryption(encription string, params map[string]string) *File {
+	file.Encryption = sql.NullString{
 		Valid: false,
 	}
-	if len(encription) > 0 && constants.IsValidCipher(encription) {
-		e.Valid = true
-		e.Int32 = constants.ChiperToInt(encription)
+	file.EncryptionParams = ""
+
+	if cipher := constants.GetCipher(encription); cipher != nil {
+		file.Encryption.Valid = true
+		file.Encryption.String = cipher.Name
+
+		if len(params) > 0 {
+			b, err := json.Marshal(params)
+			if err == nil {
+				file.EncryptionParams = string(b)
+			}
+		}
 	}
 
-	file.Encryption = e
 	return file
 }
 
+//IsEncrypted return true if the file is encrypted by the client
+func (file File) IsEncrypted() bool {
+	return file.Encryption.Valid && constants.IsValidCipher(file.Encryption.String)
+}
+
+//GetEncryption returns the cipher and its parameters. The cipher is empty if the file isn't encrypted
+func (file File) GetEncryption() (string, map[string]string) {
+	if !file.IsEncrypted() {
+		return "", nil
+	}
+
+	var params map[string]string
+	if len(file.EncryptionParams) > 0 {
+		if err := json.Unmarshal([]byte(file.EncryptionParams), &params); err != nil {
+			log.Error(err)
+		}
+	}
+
+	return file.Encryption.String, params
+}
+
+//MigrateEncryption converts ciphers stored by their position to their names
+func MigrateEncryption(db *gorm.DB) error {
+	var dataType string
+	err := db.Raw("SELECT data_type FROM information_schema.columns WHERE table_name = 'files' AND column_name = 'encryption'").Row().Scan(&dataType)
+	if err != nil {
+		return err
+	}
+
+	// Already migrated
+	if dataType == "text" {
+		return nil
+	}
+
+	// Unknown values are dropped. DDL doesn't support parameters
+	query := "ALTER TABLE files ALTER COLUMN encryption TYPE text USING CASE encryption"
+	for id, name := range constants.LegacyCiphers {
+		query += fmt.Sprintf(" WHEN %d THEN '%s'", id, name)
+	}
+	query += " END"
+
+	return db.Exec(query).Error
+}
+
+//ApplyNamespaceDefaults applies the default settings of the namespace to a new file
+func (file *File) ApplyNamespaceDefaults(namespace *Namespace, user *User) {
+	// Add default tags
+	for _, tag := range splitList(namespace.DefaultTags) {
+		if !file.HasTag(tag) {
+			file.Tags = append(file.Tags, TagsFromStringArr([]string{tag}, *namespace, user)...)
+		}
+	}
+
+	// Add default groups
+	for _, group := range splitList(namespace.DefaultGroups) {
+		if !file.HasGroup(group) {
+			file.Groups = append(file.Groups, GroupsFromStringArr([]string{group}, *namespace, user)...)
+		}
+	}
+
+	// Set expiry
+	if file.ExpiresAt == nil {
+		file.ExpiresAt = namespace.GetDefaultExpiry()
+	}
+}
+
+//IsExpired return true if the file is expired
+func (file File) IsExpired() bool {
+	return file.ExpiresAt != nil && file.ExpiresAt.Before(time.Now())
+}
+
+//DeleteExpiredFiles deletes all expired files
+func DeleteExpiredFiles(db *gorm.DB, config *Config) (int, error) {
+	var files []File
+	err := db.Model(&File{}).Where("expires_at < ?", time.Now()).Find(&files).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range files {
+		if err = files[i].Delete(db, config); err != nil {
+			return i, err
+		}
+	}
+
+	return len(files), nil
+}
+
 //SetUniqueFilename sets unique filename
 func (file *File) SetUniqueFilename(db *gorm.DB) bool {
 	var localName string
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//FileContent the extracted text of a file used for full-text search
+type FileContent struct {
+	FileID    uint   `gorm:"primary_key;auto_increment:false"`
+	Content   string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
+
+//SearchResult a file matching a full-text search
+type SearchResult struct {
+	File
+	Rank    float64
+	Snippet string
+}
+
+//CreateSearchIndex creates the tsvector column and its index
+func CreateSearchIndex(db *gorm.DB) error {
+	err := db.Exec("ALTER TABLE file_contents ADD COLUMN IF NOT EXISTS search tsvector").Error
+	if err != nil {
+		return err
+	}
+
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_file_contents_search ON file_contents USING GIN(search)").Error
+}
+
+//SetFileContent stores the text of a file and updates its search vector
+func SetFileContent(db *gorm.DB, config *Config, fileID uint, content string) error {
+	return db.Exec(`INSERT INTO file_contents (file_id, content, updated_at, search) VALUES (?, ?, ?, to_tsvector(?::regconfig, ?))
+		ON CONFLICT (file_id) DO UPDATE SET content = EXCLUDED.content, updated_at = EXCLUDED.updated_at, search = EXCLUDED.search`,
+		fileID, content, time.Now(), config.Server.Search.Language, content).Error
+}
+
+//DeleteFileContent removes the text of a file from the search index
+func DeleteFileContent(db *gorm.DB, fileID uint) error {
+	return db.Delete(&FileContent{}, "file_id = ?", fileID).Error
+}
+
+//SearchFiles runs a full-text search. Use filter to restrict the searched files
+func SearchFiles(db *gorm.DB, config *Config, query string, limit uint, filter func(*gorm.DB) *gorm.DB) ([]SearchResult, error) {
+	language := config.Server.Search.Language
+
+	search := db.Table("file_contents").
+		Select(`files.*, ts_rank(file_contents.search, websearch_to_tsquery(?::regconfig, ?)) AS rank,
+			ts_headline(?::regconfig, file_contents.content, websearch_to_tsquery(?::regconfig, ?), 'MaxFragments=2, MinWords=5, MaxWords=20') AS snippet`,
+			language, query, language, language, query).
+		Joins("INNER JOIN files ON files.id = file_contents.file_id AND files.deleted_at IS NULL").
+		Joins("INNER JOIN namespaces ON namespaces.id = files.namespace_id").
+		Where("file_contents.search @@ websearch_to_tsquery(?::regconfig, ?)", language, query).
+		Where("files.encryption IS NULL")
+
+	search = filter(search)
+
+	var results []SearchResult
+	err := search.Order("rank DESC").Limit(limit).Scan(&results).Error
+	return results, err
+}
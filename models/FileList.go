@@ -0,0 +1,172 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//MaxListLimit max amount of files returned by one list request
+const MaxListLimit = 1000
+
+//ErrInvalidCursor error if a cursor can't be used
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+//ErrInvalidOrder error if a file can't be ordered by the given attribute
+var ErrInvalidOrder = errors.New("invalid order")
+
+//Columns files can be ordered by
+var fileOrderColumns = map[string]string{
+	"":        "files.id",
+	"id":      "files.id",
+	"name":    "files.name",
+	"size":    "files.file_size",
+	"created": "files.created_at",
+	"type":    "files.file_type",
+}
+
+//Position in a file listing
+type fileCursor struct {
+	Order string `json:"o"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+//IsValidFileOrder return true if files can be ordered by order
+func IsValidFileOrder(order string) bool {
+	_, ok := fileOrderColumns[order]
+	return ok
+}
+
+//FilterFilesByTags adds a filter for files having one of the tags or their children to the query
+func FilterFilesByTags(query *gorm.DB, tags []string) *gorm.DB {
+	if len(tags) == 0 {
+		return query
+	}
+
+	condition, args := tagCondition("tags.name", tags)
+	return query.Where(`files.id IN (SELECT files_tags.file_id FROM files_tags
+		INNER JOIN tags ON tags.id = files_tags.tag_id
+		WHERE `+condition+` AND tags.deleted_at IS NULL)`, args...)
+}
+
+//FilterFilesByGroups adds a filter for files being in one of the groups to the query
+func FilterFilesByGroups(query *gorm.DB, groups []string) *gorm.DB {
+	if len(groups) == 0 {
+		return query
+	}
+
+	return query.Where(`files.id IN (SELECT files_groups.file_id FROM files_groups
+		INNER JOIN "groups" ON "groups".id = files_groups.group_id
+		WHERE "groups".name IN (?) AND "groups".deleted_at IS NULL)`, groups)
+}
+
+//PaginateFiles runs the file query using the ordering and pagination of params.
+//Returns the files, a cursor for the next page (empty if there is none) and the total count if requested
+func PaginateFiles(query *gorm.DB, params OptionalRequetsParameter) ([]File, string, *int64, error) {
+	column, ok := fileOrderColumns[params.OrderBy]
+	if !ok {
+		return nil, "", nil, ErrInvalidOrder
+	}
+
+	// Count all matching files
+	var total *int64
+	if params.Total {
+		var count int64
+		if err := query.Model(&File{}).Count(&count).Error; err != nil {
+			return nil, "", nil, err
+		}
+		total = &count
+	}
+
+	// Continue after cursor
+	if len(params.Cursor) > 0 {
+		cursor, err := decodeFileCursor(params.Cursor)
+		if err != nil || cursor.Order != params.OrderBy || cursor.Desc != params.Desc {
+			return nil, "", nil, ErrInvalidCursor
+		}
+
+		operator := ">"
+		if params.Desc {
+			operator = "<"
+		}
+
+		if column == "files.id" {
+			query = query.Where("files.id "+operator+" ?", cursor.ID)
+		} else {
+			query = query.Where("("+column+", files.id) "+operator+" (?, ?)", cursor.Value, cursor.ID)
+		}
+	}
+
+	// Order
+	direction := " ASC"
+	if params.Desc {
+		direction = " DESC"
+	}
+	query = query.Order(column + direction)
+	if column != "files.id" {
+		query = query.Order("files.id" + direction)
+	}
+
+	// Limit. Request one more to know if there is a next page
+	limit := params.Limit
+	if limit == 0 || limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+	query = query.Limit(limit + 1)
+
+	var files []File
+	if err := query.Find(&files).Error; err != nil {
+		return nil, "", nil, err
+	}
+
+	// Create cursor if there are more files
+	var nextCursor string
+	if uint(len(files)) > limit {
+		files = files[:limit]
+		nextCursor = encodeFileCursor(params, files[len(files)-1])
+	}
+
+	return files, nextCursor, total, nil
+}
+
+func encodeFileCursor(params OptionalRequetsParameter, last File) string {
+	cursor := fileCursor{
+		Order: params.OrderBy,
+		Desc:  params.Desc,
+		ID:    last.ID,
+	}
+
+	switch params.OrderBy {
+	case "name":
+		cursor.Value = last.Name
+	case "size":
+		cursor.Value = strconv.FormatInt(last.FileSize, 10)
+	case "created":
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case "type":
+		cursor.Value = last.FileType
+	}
+
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeFileCursor(s string) (*fileCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor fileCursor
+	if err = json.Unmarshal(b, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPaginateFiles(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	var expected []string
+	for i := 0; i < 7; i++ {
+		file := &File{Name: fmt.Sprintf("file%d", i%3), LocalName: fmt.Sprint(i), FileSize: int64(i), Namespace: namespace, NamespaceID: namespace.ID}
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, fmt.Sprintf("%s/%d", file.Name, file.ID))
+	}
+	sort.Strings(expected)
+
+	// Walk all pages ordered by name
+	params := OptionalRequetsParameter{Limit: 3, OrderBy: "name", Total: true}
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination doesn't end")
+		}
+
+		files, cursor, total, err := PaginateFiles(db.Model(&File{}), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total == nil || *total != 7 {
+			t.Fatalf("expected total of 7, got %v", total)
+		}
+
+		for _, file := range files {
+			names = append(names, fmt.Sprintf("%s/%d", file.Name, file.ID))
+		}
+
+		if len(cursor) == 0 {
+			break
+		}
+		params.Cursor = cursor
+	}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	// Cursors only work with their order
+	params.OrderBy = "size"
+	if _, _, _, err := PaginateFiles(db.Model(&File{}), params); err != ErrInvalidCursor {
+		t.Errorf("expected invalid cursor, got %v", err)
+	}
+}
+
+func TestPaginateFilesWithoutLimit(t *testing.T) {
+	db := newTestDB(t)
+	_, namespace := newTestNamespace(t, db, "user_test")
+
+	for i := 0; i <= MaxListLimit; i++ {
+		file := &File{Name: "file", LocalName: fmt.Sprint(i), NamespaceID: namespace.ID}
+		if err := db.Create(file).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, cursor, _, err := PaginateFiles(db.Model(&File{}), OptionalRequetsParameter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != MaxListLimit || len(cursor) == 0 {
+		t.Fatalf("expected %d files and a cursor, got %d files", MaxListLimit, len(files))
+	}
+
+	files, cursor, _, err = PaginateFiles(db.Model(&File{}), OptionalRequetsParameter{Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || len(cursor) > 0 {
+		t.Errorf("expected last file without cursor, got %d files", len(files))
+	}
+}
+
+func TestFindFilesByAttributes(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+	namespace.User = user
+
+	files := []struct {
+		name   string
+		tags   []string
+		groups []string
+	}{
+		{"a", []string{"photos"}, []string{"family"}},
+		{"b", []string{"photos/2020"}, nil},
+		{"c", []string{"videos"}, []string{"family"}},
+		{"d", nil, nil},
+	}
+	for _, f := range files {
+		file := &File{Name: f.name, LocalName: f.name, Namespace: namespace, NamespaceID: namespace.ID}
+		for _, tag := range f.tags {
+			file.Tags = append(file.Tags, *GetTag(db, tag, namespace, user))
+		}
+		for _, group := range f.groups {
+			file.Groups = append(file.Groups, *GetGroup(db, group, namespace, user))
+		}
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		tags   []string
+		groups []string
+		names  []string
+	}{
+		{nil, nil, []string{"a", "b", "c", "d"}},
+		{[]string{"photos"}, nil, []string{"a", "b"}},
+		{[]string{"photos/2020", "videos"}, nil, []string{"b", "c"}},
+		{nil, []string{"family"}, []string{"a", "c"}},
+		{[]string{"photos"}, []string{"family"}, []string{"a"}},
+		{[]string{"music"}, nil, nil},
+	}
+
+	for _, test := range tests {
+		found, err := FindFiles(db, File{Namespace: namespace}, nil, test.tags, test.groups)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, file := range found {
+			names = append(names, file.Name)
+		}
+		sort.Strings(names)
+
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("tags %v groups %v: expected %v, got %v", test.tags, test.groups, test.names, names)
+		}
+	}
+}
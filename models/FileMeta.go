@@ -0,0 +1,186 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+//MetaType type of a metadata value
+type MetaType uint8
+
+//Metadata types
+const (
+	StringMetaType MetaType = iota
+	IntMetaType
+	FloatMetaType
+	BoolMetaType
+)
+
+//MaxMetaKeyLength max length of a metadata key
+const MaxMetaKeyLength = 64
+
+var metaKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+//ErrInvalidMetaKey error if a metadata key is invalid
+var ErrInvalidMetaKey = errors.New("metadata keys can only contain letters, numbers, '_' and '-'")
+
+//FileMeta a custom key/value field of a file
+type FileMeta struct {
+	ID     uint     `gorm:"primary_key"`
+	FileID uint     `gorm:"unique_index:idx_file_meta_key;not null"`
+	Key    string   `gorm:"unique_index:idx_file_meta_key;not null"`
+	Value  string   `gorm:"type:text"`
+	Type   MetaType `gorm:"type:smallint"`
+}
+
+//TableName table name of FileMeta
+func (FileMeta) TableName() string {
+	return "file_meta"
+}
+
+//GetValue return the value converted into its type
+func (meta FileMeta) GetValue() interface{} {
+	switch meta.Type {
+	case IntMetaType:
+		if i, err := strconv.ParseInt(meta.Value, 10, 64); err == nil {
+			return i
+		}
+	case FloatMetaType:
+		if f, err := strconv.ParseFloat(meta.Value, 64); err == nil {
+			return f
+		}
+	case BoolMetaType:
+		if b, err := strconv.ParseBool(meta.Value); err == nil {
+			return b
+		}
+	}
+
+	return meta.Value
+}
+
+//NewFileMeta creates metadata from a json value
+func NewFileMeta(key string, value interface{}) (*FileMeta, error) {
+	if len(key) > MaxMetaKeyLength || !metaKeyRegex.MatchString(key) {
+		return nil, ErrInvalidMetaKey
+	}
+
+	meta := FileMeta{
+		Key: key,
+	}
+
+	switch v := value.(type) {
+	case string:
+		meta.Value = v
+		meta.Type = StringMetaType
+	case bool:
+		meta.Value = strconv.FormatBool(v)
+		meta.Type = BoolMetaType
+	case float64:
+		// JSON numbers are always floats
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			meta.Value = strconv.FormatInt(int64(v), 10)
+			meta.Type = IntMetaType
+		} else {
+			meta.Value = strconv.FormatFloat(v, 'f', -1, 64)
+			meta.Type = FloatMetaType
+		}
+	case int:
+		meta.Value = strconv.Itoa(v)
+		meta.Type = IntMetaType
+	case int64:
+		meta.Value = strconv.FormatInt(v, 10)
+		meta.Type = IntMetaType
+	default:
+		return nil, fmt.Errorf("unsupported value for '%s'", key)
+	}
+
+	return &meta, nil
+}
+
+//MetaToMap return a map containing all metadata
+func MetaToMap(metas []FileMeta) map[string]interface{} {
+	if len(metas) == 0 {
+		return nil
+	}
+
+	m := make(map[string]interface{}, len(metas))
+	for _, meta := range metas {
+		m[meta.Key] = meta.GetValue()
+	}
+	return m
+}
+
+//ValidateMeta return an error if metadata can't be stored
+func ValidateMeta(m map[string]interface{}) error {
+	for key, value := range m {
+		if _, err := NewFileMeta(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//SetMeta sets or replaces metadata of the file
+func (file *File) SetMeta(db *gorm.DB, m map[string]interface{}) error {
+	for key, value := range m {
+		meta, err := NewFileMeta(key, value)
+		if err != nil {
+			return err
+		}
+		meta.FileID = file.ID
+
+		// Update existing value
+		var existing FileMeta
+		err = db.Where(&FileMeta{FileID: file.ID, Key: key}).First(&existing).Error
+		if err == nil {
+			meta.ID = existing.ID
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err = db.Save(meta).Error; err != nil {
+			return err
+		}
+	}
+
+	return file.LoadMeta(db)
+}
+
+//RemoveMeta removes metadata from the file
+func (file *File) RemoveMeta(db *gorm.DB, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := db.Where("file_id = ? AND key IN (?)", file.ID, keys).Delete(&FileMeta{}).Error
+	if err != nil {
+		return err
+	}
+
+	return file.LoadMeta(db)
+}
+
+//LoadMeta loads the metadata of the file
+func (file *File) LoadMeta(db *gorm.DB) error {
+	file.Meta = nil
+	return db.Where("file_id = ?", file.ID).Find(&file.Meta).Error
+}
+
+//FilterFilesByMeta adds a filter for files having all the metadata values to the query
+func FilterFilesByMeta(query *gorm.DB, m map[string]interface{}) (*gorm.DB, error) {
+	for key, value := range m {
+		meta, err := NewFileMeta(key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where("files.id IN (SELECT file_id FROM file_meta WHERE key = ? AND value = ?)", meta.Key, meta.Value)
+	}
+
+	return query, nil
+}
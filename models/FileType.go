@@ -0,0 +1,80 @@
+package models
+
+import "strings"
+
+//AttachmentTypes are never served inline. Browsers would run their scripts on the domain of the server
+var AttachmentTypes = []string{
+	"text/html",
+	"application/xhtml+xml",
+	"image/svg+xml",
+	"text/xml",
+	"application/xml",
+	".html",
+	".htm",
+	".xhtml",
+	".svg",
+	".svgz",
+	".xml",
+}
+
+//Return true if the pattern matches the mime type (image/*) or the extension (.exe) of a file
+func matchType(pattern, mime, name string) bool {
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, ".") {
+		return strings.HasSuffix(strings.ToLower(name), pattern)
+	}
+
+	return matchMime(pattern, strings.ToLower(mime))
+}
+
+//Return true if one of the patterns matches the file
+func matchesTypes(patterns []string, mime, name string) bool {
+	for _, pattern := range patterns {
+		if matchType(pattern, mime, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Return true if the file is in the comma separated list allowed and not in denied. An empty allowed list allows all types
+func isTypeAllowed(allowed, denied string, mime, name string) bool {
+	if allowedTypes := splitList(allowed); len(allowedTypes) > 0 && !matchesTypes(allowedTypes, mime, name) {
+		return false
+	}
+
+	return !matchesTypes(splitList(denied), mime, name)
+}
+
+//CanUpload return true if the user is allowed to upload the type of the file into its namespace
+func (file File) CanUpload(user *User) bool {
+	if file.Namespace != nil && !file.Namespace.IsUploadAllowed(file.FileType, file.Name) {
+		return false
+	}
+
+	return user == nil || user.Role == nil || user.Role.IsUploadAllowed(file.FileType, file.Name)
+}
+
+//CanPublish return true if the user is allowed to publish the type of the file in its namespace
+func (file File) CanPublish(user *User) bool {
+	if file.Namespace != nil && !file.Namespace.IsPublishAllowed(file.FileType, file.Name) {
+		return false
+	}
+
+	return user == nil || user.Role == nil || user.Role.IsPublishAllowed(file.FileType, file.Name)
+}
+
+//ServeInline return true if the file can be displayed by browsers. Otherwise it has to be sent as attachment.
+//The rules of the namespace and the role of the uploader are only applied if they are loaded
+func (file File) ServeInline() bool {
+	if matchesTypes(AttachmentTypes, file.FileType, file.Name) {
+		return false
+	}
+
+	if file.Namespace != nil && !file.Namespace.IsInlineAllowed(file.FileType, file.Name) {
+		return false
+	}
+
+	return file.User == nil || file.User.Role == nil || file.User.Role.IsInlineAllowed(file.FileType, file.Name)
+}
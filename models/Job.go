@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+//JobType type of job
+type JobType uint8
+
+//Job types
+const (
+	NamespaceDeleteJobType JobType = iota
+	RuleApplyJobType
+	URLDownloadJobType
+	RescanJobType
+)
+
+//JobState state of a job
+type JobState uint8
+
+//Job states
+const (
+	JobPending JobState = iota
+	JobRunning
+	JobDone
+	JobFailed
+	JobCanceled
+)
+
+//JobTypeNames names of the jobtypes
+var JobTypeNames = map[JobType]string{
+	NamespaceDeleteJobType: "namespace delete",
+	RuleApplyJobType:       "apply rules",
+	URLDownloadJobType:     "url download",
+	RescanJobType:          "rescan",
+}
+
+//JobStateNames names of the jobstates
+var JobStateNames = map[JobState]string{
+	JobPending:  "pending",
+	JobRunning:  "running",
+	JobDone:     "done",
+	JobFailed:   "failed",
+	JobCanceled: "canceled",
+}
+
+//Cancel functions of the jobs running on this server
+var (
+	jobCancelFuncs = map[uint]context.CancelFunc{}
+	jobCancelMutex sync.Mutex
+)
+
+//Job a background job
+type Job struct {
+	gorm.Model
+	Type     JobType  `gorm:"type:smallint"`
+	State    JobState `gorm:"type:smallint"`
+	UserID   uint     `sql:"index" gorm:"not null"`
+	User     *User    `gorm:"association_autoupdate:false;association_autocreate:false"`
+	Progress int64
+	Total    int64
+	Message  string
+	FileID   uint
+}
+
+//NewJob creates a new pending job
+func NewJob(db *gorm.DB, jobType JobType, user *User) (*Job, error) {
+	job := Job{
+		Type:   jobType,
+		State:  JobPending,
+		UserID: user.ID,
+	}
+
+	if err := db.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+//FindJob finds a job of a user
+func FindJob(db *gorm.DB, jobID uint, user *User) (*Job, error) {
+	var job Job
+	err := db.Model(&Job{}).Where("id = ? AND user_id = ?", jobID, user.ID).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+//Start sets the job running
+func (job *Job) Start(db *gorm.DB, total int64) error {
+	job.State = JobRunning
+	job.Total = total
+	return db.Save(job).Error
+}
+
+//SetProgress updates the progress of the job
+func (job *Job) SetProgress(db *gorm.DB, progress int64) error {
+	job.Progress = progress
+	return db.Model(job).UpdateColumn("progress", progress).Error
+}
+
+//Done sets the job done
+func (job *Job) Done(db *gorm.DB, message string) error {
+	job.State = JobDone
+	job.Progress = job.Total
+	job.Message = message
+	return db.Save(job).Error
+}
+
+//Fail sets the job failed
+func (job *Job) Fail(db *gorm.DB, err error) error {
+	job.State = JobFailed
+	job.Message = err.Error()
+	return db.Save(job).Error
+}
+
+//Cancel sets the job canceled
+func (job *Job) Cancel(db *gorm.DB) error {
+	job.State = JobCanceled
+	job.Message = "canceled"
+	return db.Save(job).Error
+}
+
+//IsFinished return true if job is done, failed or canceled
+func (job Job) IsFinished() bool {
+	return job.State == JobDone || job.State == JobFailed || job.State == JobCanceled
+}
+
+//WithCancel returns a context which gets canceled by CancelJob.
+//release has to be called if the job is finished
+func (job Job) WithCancel() (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	jobCancelMutex.Lock()
+	jobCancelFuncs[job.ID] = cancel
+	jobCancelMutex.Unlock()
+
+	return ctx, func() {
+		jobCancelMutex.Lock()
+		delete(jobCancelFuncs, job.ID)
+		jobCancelMutex.Unlock()
+		cancel()
+	}
+}
+
+//CancelJob cancels a running job. Returns false if the job isn't running on this server
+func CancelJob(jobID uint) bool {
+	jobCancelMutex.Lock()
+	defer jobCancelMutex.Unlock()
+
+	cancel, ok := jobCancelFuncs[jobID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+//ToResponse returns a response item for the job
+func (job Job) ToResponse() JobResponse {
+	return JobResponse{
+		ID:       job.ID,
+		Type:     JobTypeNames[job.Type],
+		State:    JobStateNames[job.State],
+		Progress: job.Progress,
+		Total:    job.Total,
+		Message:  job.Message,
+		FileID:   job.FileID,
+		Created:  job.CreatedAt,
+	}
+}
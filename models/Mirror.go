@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//MinRefreshInterval smallest allowed interval for refreshing mirrored files
+const MinRefreshInterval = 5 * time.Minute
+
+//ErrRefreshTooShort error if a refresh interval is smaller than MinRefreshInterval
+var ErrRefreshTooShort = errors.New("refresh interval must be at least " + MinRefreshInterval.String())
+
+//ParseRefreshInterval parses a refresh interval like "12h" or "7d". Empty intervals return 0
+func ParseRefreshInterval(interval string) (time.Duration, error) {
+	refresh, err := ParseExpiry(interval)
+	if err != nil {
+		return 0, err
+	}
+
+	if refresh > 0 && refresh < MinRefreshInterval {
+		return 0, ErrRefreshTooShort
+	}
+
+	return refresh, nil
+}
+
+//SetMirror lets the file be refreshed from url every interval. Doesn't save it
+func (file *File) SetMirror(url string, interval time.Duration) {
+	// Cache headers of a different url are useless
+	if file.SourceURL != url {
+		file.ETag = ""
+		file.LastModified = ""
+		file.Checksum = ""
+	}
+
+	nextRefresh := time.Now().Add(interval)
+	file.SourceURL = url
+	file.RefreshInterval = interval
+	file.NextRefresh = &nextRefresh
+}
+
+//StopMirror stops refreshing the file. Doesn't save it
+func (file *File) StopMirror() {
+	file.SourceURL = ""
+	file.RefreshInterval = 0
+	file.NextRefresh = nil
+	file.ETag = ""
+	file.LastModified = ""
+	file.Checksum = ""
+}
+
+//IsMirror return true if the file gets refreshed from its source url
+func (file File) IsMirror() bool {
+	return len(file.SourceURL) > 0 && file.RefreshInterval > 0
+}
+
+//FindFilesToRefresh finds mirrored files which have to be refreshed
+func FindFilesToRefresh(db *gorm.DB, limit uint) ([]File, error) {
+	var files []File
+	err := db.Preload("User").Preload("User.Role").
+		Where("refresh_interval > 0 AND next_refresh <= ?", time.Now()).
+		Order("next_refresh").
+		Limit(limit).
+		Find(&files).Error
+
+	return files, err
+}
+
+//ScheduleRefresh sets the next refresh of a mirrored file
+func (file *File) ScheduleRefresh(db *gorm.DB) error {
+	nextRefresh := time.Now().Add(file.RefreshInterval)
+	file.NextRefresh = &nextRefresh
+	return db.Model(file).UpdateColumn("next_refresh", nextRefresh).Error
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -9,6 +12,12 @@ import (
 //DefaultNamespace defalut namespace
 var DefaultNamespace Namespace
 
+//Errors of files which can't be moved into a namespace
+var (
+	ErrNamespaceQuotaExceeded = errors.New("namespace quota exceeded")
+	ErrNamespaceTypeDenied    = errors.New("filetype not allowed in namespace")
+)
+
 //NamespaceType type of namespace
 type NamespaceType uint8
 
@@ -24,6 +33,39 @@ type Namespace struct {
 	Name   string `gorm:"not null"`
 	UserID uint   `gorm:"column:creator;index"`
 	User   *User  `gorm:"association_autoupdate:false;association_autocreate:false"`
+
+	// Quota. 0 means unlimited
+	MaxSize  int64
+	MaxFiles int64
+
+	// Defaults for new uploads
+	DefaultPublic bool `gorm:"default:false"`
+	DefaultTags   string
+	DefaultGroups string
+	DefaultExpiry time.Duration
+	AllowedMimes  string
+
+	// Comma separated mime types (image/*) or extensions (.exe)
+	DeniedUploadTypes   string
+	AllowedPublishTypes string
+	DeniedPublishTypes  string
+	DeniedInlineTypes   string
+}
+
+//NamespaceSettings settings of a namespace
+type NamespaceSettings struct {
+	MaxSize       int64    `json:"maxsize"`
+	MaxFiles      int64    `json:"maxfiles"`
+	DefaultPublic bool     `json:"pub"`
+	DefaultTags   []string `json:"tags,omitempty"`
+	DefaultGroups []string `json:"groups,omitempty"`
+	AllowedMimes  []string `json:"mimes,omitempty"`
+	DefaultExpiry string   `json:"expiry,omitempty"`
+
+	DeniedUploadTypes   []string `json:"deny,omitempty"`
+	AllowedPublishTypes []string `json:"pubtypes,omitempty"`
+	DeniedPublishTypes  []string `json:"denypub,omitempty"`
+	DeniedInlineTypes   []string `json:"denyinline,omitempty"`
 }
 
 //GetNamespaceFromString return namespace from string
@@ -86,3 +128,265 @@ func UserNamespaceName(namespaceName string, user *User) string {
 func (namespace *Namespace) IsValid() bool {
 	return (namespace != nil && namespace.ID > 0)
 }
+
+//GetSettings return the settings of the namespace
+func (namespace Namespace) GetSettings() NamespaceSettings {
+	settings := NamespaceSettings{
+		MaxSize:       namespace.MaxSize,
+		MaxFiles:      namespace.MaxFiles,
+		DefaultPublic: namespace.DefaultPublic,
+		DefaultTags:   splitList(namespace.DefaultTags),
+		DefaultGroups: splitList(namespace.DefaultGroups),
+		AllowedMimes:  splitList(namespace.AllowedMimes),
+
+		DeniedUploadTypes:   splitList(namespace.DeniedUploadTypes),
+		AllowedPublishTypes: splitList(namespace.AllowedPublishTypes),
+		DeniedPublishTypes:  splitList(namespace.DeniedPublishTypes),
+		DeniedInlineTypes:   splitList(namespace.DeniedInlineTypes),
+	}
+
+	if namespace.DefaultExpiry > 0 {
+		settings.DefaultExpiry = namespace.DefaultExpiry.String()
+	}
+
+	return settings
+}
+
+//ApplySettings applies settings to the namespace. Doesn't save it
+func (namespace *Namespace) ApplySettings(settings NamespaceSettings) error {
+	expiry, err := ParseExpiry(settings.DefaultExpiry)
+	if err != nil {
+		return err
+	}
+
+	namespace.MaxSize = settings.MaxSize
+	namespace.MaxFiles = settings.MaxFiles
+	namespace.DefaultPublic = settings.DefaultPublic
+	namespace.DefaultTags = joinList(settings.DefaultTags)
+	namespace.DefaultGroups = joinList(settings.DefaultGroups)
+	namespace.AllowedMimes = joinList(settings.AllowedMimes)
+	namespace.DeniedUploadTypes = joinList(settings.DeniedUploadTypes)
+	namespace.AllowedPublishTypes = joinList(settings.AllowedPublishTypes)
+	namespace.DeniedPublishTypes = joinList(settings.DeniedPublishTypes)
+	namespace.DeniedInlineTypes = joinList(settings.DeniedInlineTypes)
+	namespace.DefaultExpiry = expiry
+
+	return nil
+}
+
+//GetUsage return the total size and count of files in the namespace
+func (namespace *Namespace) GetUsage(db *gorm.DB) (int64, int64, error) {
+	var usage struct {
+		Size  int64
+		Count int64
+	}
+
+	err := db.Model(&File{}).
+		Select("COALESCE(SUM(file_size), 0) AS size, COUNT(*) AS count").
+		Where("namespace_id = ?", namespace.ID).
+		Scan(&usage).Error
+
+	return usage.Size, usage.Count, err
+}
+
+//HasQuota return true if the namespace has a quota
+func (namespace *Namespace) HasQuota() bool {
+	return namespace.MaxSize > 0 || namespace.MaxFiles > 0
+}
+
+//CheckQuota return true if addSize bytes and addFiles files would still fit into the namespace
+func (namespace *Namespace) CheckQuota(db *gorm.DB, addSize, addFiles int64) (bool, error) {
+	if !namespace.HasQuota() {
+		return true, nil
+	}
+
+	size, count, err := namespace.GetUsage(db)
+	if err != nil {
+		return false, err
+	}
+
+	if namespace.MaxSize > 0 && size+addSize > namespace.MaxSize {
+		return false, nil
+	}
+
+	if namespace.MaxFiles > 0 && count+addFiles > namespace.MaxFiles {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+//CheckMove returns an error if the files can't be moved into the namespace because
+//of its quota or allowed file types. Files already in the namespace are ignored
+func (namespace *Namespace) CheckMove(db *gorm.DB, files []File) error {
+	var addSize, addFiles int64
+	for i := range files {
+		if files[i].NamespaceID == namespace.ID {
+			continue
+		}
+
+		if !namespace.IsUploadAllowed(files[i].FileType, files[i].Name) {
+			return ErrNamespaceTypeDenied
+		}
+
+		addSize += files[i].FileSize
+		addFiles++
+	}
+
+	ok, err := namespace.CheckQuota(db, addSize, addFiles)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNamespaceQuotaExceeded
+	}
+
+	return nil
+}
+
+//IsUploadAllowed return true if files of the given mime type and name can be uploaded into the namespace
+func (namespace *Namespace) IsUploadAllowed(mime, name string) bool {
+	return isTypeAllowed(namespace.AllowedMimes, namespace.DeniedUploadTypes, mime, name)
+}
+
+//IsPublishAllowed return true if files of the given mime type and name can be published in the namespace
+func (namespace *Namespace) IsPublishAllowed(mime, name string) bool {
+	return isTypeAllowed(namespace.AllowedPublishTypes, namespace.DeniedPublishTypes, mime, name)
+}
+
+//IsInlineAllowed return true if public files of the namespace with the given mime type and name can be displayed by browsers
+func (namespace *Namespace) IsInlineAllowed(mime, name string) bool {
+	return isTypeAllowed("", namespace.DeniedInlineTypes, mime, name)
+}
+
+//GetDefaultExpiry return the time a new file in the namespace expires. Nil if files don't expire
+func (namespace *Namespace) GetDefaultExpiry() *time.Time {
+	if namespace.DefaultExpiry <= 0 {
+		return nil
+	}
+
+	expiry := time.Now().Add(namespace.DefaultExpiry)
+	return &expiry
+}
+
+//Delete deletes the namespace with all its files, tags and groups and reports the progress to job.
+//If moveTo is set, the files are moved into this namespace instead of being deleted
+func (namespace *Namespace) Delete(db *gorm.DB, config *Config, job *Job, moveTo *Namespace, user *User) error {
+	var files []File
+	err := db.Model(&File{}).
+		Where("namespace_id = ?", namespace.ID).
+		Preload("Tags").
+		Preload("Groups").
+		Find(&files).Error
+	if err != nil {
+		job.Fail(db, err)
+		return err
+	}
+
+	if moveTo != nil {
+		if err = moveTo.CheckMove(db, files); err != nil {
+			job.Fail(db, err)
+			return err
+		}
+	}
+
+	if err = job.Start(db, int64(len(files))); err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+	if err = namespace.deleteRows(tx, files, moveTo, user); err != nil {
+		tx.Rollback()
+		job.Fail(db, err)
+		return err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		job.Fail(db, err)
+		return err
+	}
+
+	if moveTo != nil {
+		return job.Done(db, fmt.Sprintf("moved %d files to %s", len(files), moveTo.Name))
+	}
+
+	// Shred file contents after the transaction was committed
+	for i := range files {
+		if files[i].Quarantined {
+			ShredQuarantinedFile(config, files[i].LocalName)
+		} else {
+			ShredLocalFile(config, files[i].LocalName)
+		}
+		job.SetProgress(db, int64(i+1))
+	}
+
+	return job.Done(db, fmt.Sprintf("deleted %d files", len(files)))
+}
+
+//Delete or move all rows of the namespace
+func (namespace *Namespace) deleteRows(tx *gorm.DB, files []File, moveTo *Namespace, user *User) error {
+	if moveTo != nil {
+		// Move files into the new namespace
+		for i := range files {
+			if err := files[i].UpdateNamespace(tx, moveTo, user); err != nil {
+				return err
+			}
+		}
+	} else {
+		// Remove associations, metadata and search index of files
+		err := tx.Exec("DELETE FROM files_tags WHERE file_id IN (SELECT id FROM files WHERE namespace_id = ?)", namespace.ID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM file_contents WHERE file_id IN (SELECT id FROM files WHERE namespace_id = ?)", namespace.ID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM files_groups WHERE file_id IN (SELECT id FROM files WHERE namespace_id = ?)", namespace.ID).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("DELETE FROM file_meta WHERE file_id IN (SELECT id FROM files WHERE namespace_id = ?)", namespace.ID).Error
+		if err != nil {
+			return err
+		}
+
+		// Remove public filenames to free these keywords
+		err = tx.Model(&File{}).Where("namespace_id = ?", namespace.ID).Updates(map[string]interface{}{
+			"is_public":       false,
+			"public_filename": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Delete(&File{}, "namespace_id = ?", namespace.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	// Remove remaining associations of tags and groups
+	err := tx.Exec("DELETE FROM files_tags WHERE tag_id IN (SELECT id FROM tags WHERE namespace_id = ?)", namespace.ID).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Exec(`DELETE FROM files_groups WHERE group_id IN (SELECT id FROM "groups" WHERE namespace_id = ?)`, namespace.ID).Error
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Delete(&Tag{}, "namespace_id = ?", namespace.ID).Error; err != nil {
+		return err
+	}
+	if err = tx.Delete(&Group{}, "namespace_id = ?", namespace.ID).Error; err != nil {
+		return err
+	}
+	if err = tx.Delete(&Rule{}, "namespace_id = ?", namespace.ID).Error; err != nil {
+		return err
+	}
+	if err = tx.Unscoped().Delete(&Collection{}, "namespace_id = ?", namespace.ID).Error; err != nil {
+		return err
+	}
+
+	return tx.Delete(namespace).Error
+}
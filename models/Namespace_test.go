@@ -0,0 +1,122 @@
+package models
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestNamespaceDeleteShredsQuarantinedFiles(t *testing.T) {
+	db := newTestDB(t)
+	config := newTestConfig(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	// A stored and a quarantined file
+	stored := &File{Name: "stored.txt", LocalName: "stored", Namespace: namespace, NamespaceID: namespace.ID}
+	infected := &File{Name: "infected.exe", LocalName: "infected", Namespace: namespace, NamespaceID: namespace.ID}
+	for _, file := range []*File{stored, infected} {
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(config.GetStorageFile(file.LocalName), []byte(file.Name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infected.User = user
+	if err := infected.Quarantine(db, config, "Eicar-Test-Signature"); err != nil {
+		t.Fatal(err)
+	}
+	quarantineFile := config.GetQuarantineFile(infected.LocalName)
+	if !fileExists(quarantineFile) || !fileExists(quarantineFile+".json") {
+		t.Fatal("file wasn't quarantined")
+	}
+
+	job, err := NewJob(db, NamespaceDeleteJobType, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = namespace.Delete(db, config, job, nil, user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{config.GetStorageFile(stored.LocalName), quarantineFile, quarantineFile + ".json"} {
+		if fileExists(file) {
+			t.Errorf("%s wasn't removed", file)
+		}
+	}
+
+	var count int
+	if err = db.Model(&File{}).Where("namespace_id = ?", namespace.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d files weren't deleted", count)
+	}
+}
+
+func TestNamespaceCheckMove(t *testing.T) {
+	db := newTestDB(t)
+	user, source := newTestNamespace(t, db, "user_source")
+
+	target := &Namespace{Name: "user_target", UserID: user.ID, MaxSize: 100, MaxFiles: 2, AllowedMimes: "image/*"}
+	if err := db.Create(target).Error; err != nil {
+		t.Fatal(err)
+	}
+	stored := &File{Name: "stored.png", LocalName: "stored", FileType: "image/png", FileSize: 40, Namespace: target, NamespaceID: target.ID}
+	if err := stored.Insert(db, user); err != nil {
+		t.Fatal(err)
+	}
+
+	image := File{Name: "a.png", FileType: "image/png", FileSize: 50, NamespaceID: source.ID}
+	tests := []struct {
+		name  string
+		files []File
+		err   error
+	}{
+		{"fits", []File{image}, nil},
+		{"too large", []File{image, image}, ErrNamespaceQuotaExceeded},
+		{"too many files", []File{{Name: "b.png", FileType: "image/png", NamespaceID: source.ID}, {Name: "c.png", FileType: "image/png", NamespaceID: source.ID}}, ErrNamespaceQuotaExceeded},
+		{"type not allowed", []File{{Name: "a.exe", FileType: "application/x-msdownload", NamespaceID: source.ID}}, ErrNamespaceTypeDenied},
+		{"already in namespace", []File{*stored}, nil},
+	}
+
+	for _, test := range tests {
+		if err := target.CheckMove(db, test.files); err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestNamespaceDeleteMoveChecksQuota(t *testing.T) {
+	db := newTestDB(t)
+	config := newTestConfig(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	target := &Namespace{Name: "user_target", UserID: user.ID, MaxFiles: 1}
+	if err := db.Create(target).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		file := &File{Name: name, LocalName: name, Namespace: namespace, NamespaceID: namespace.ID}
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job, err := NewJob(db, NamespaceDeleteJobType, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = namespace.Delete(db, config, job, target, user); err != ErrNamespaceQuotaExceeded {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+
+	var count int
+	if err = db.Model(&File{}).Where("namespace_id = ?", namespace.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("files were moved: %d left", count)
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "strings"
+import (
+	"path/filepath"
+	"strings"
+)
 
 //PreviewMimes mimes assigned to preview
 var PreviewMimes map[PreviewType][]string = map[PreviewType][]string{
@@ -10,6 +13,60 @@ var PreviewMimes map[PreviewType][]string = map[PreviewType][]string{
 	TextPreviewType: []string{
 		"text/*",
 	},
+	AudioPreviewType: []string{
+		"audio/*",
+	},
+	VideoPreviewType: []string{
+		"video/*",
+	},
+	PDFPreviewType: []string{
+		"application/pdf",
+	},
+	MarkdownPreviewType: []string{
+		"text/markdown",
+		"text/x-markdown",
+	},
+	CodePreviewType: []string{
+		"application/json",
+		"application/javascript",
+		"application/x-javascript",
+		"application/xml",
+		"application/x-sh",
+		"application/x-python",
+		"application/x-php",
+		"application/sql",
+		"application/toml",
+		"application/x-yaml",
+		"text/html",
+		"text/css",
+		"text/xml",
+		"text/javascript",
+		"text/x-go",
+		"text/x-python",
+		"text/x-c",
+		"text/x-c++",
+		"text/x-java",
+		"text/x-php",
+		"text/x-sh",
+		"text/x-lua",
+		"text/x-perl",
+		"text/x-ruby",
+		"text/x-rust",
+		"text/x-tcl",
+		"text/yaml",
+	},
+}
+
+//PreviewExtensions file extensions of text files assigned to preview
+var PreviewExtensions map[PreviewType][]string = map[PreviewType][]string{
+	MarkdownPreviewType: []string{
+		".md", ".markdown",
+	},
+	CodePreviewType: []string{
+		".go", ".py", ".js", ".ts", ".jsx", ".tsx", ".c", ".h", ".cpp", ".hpp", ".cc", ".cs", ".java", ".kt",
+		".rs", ".rb", ".php", ".pl", ".lua", ".swift", ".sh", ".bash", ".zsh", ".ps1", ".sql",
+		".json", ".yml", ".yaml", ".toml", ".ini", ".xml", ".html", ".css", ".scss", ".diff", ".patch", ".dockerfile",
+	},
 }
 
 //PreviewType type of preview
@@ -20,6 +77,11 @@ const (
 	DefaultPreviewType PreviewType = iota
 	ImagePreviewType
 	TextPreviewType
+	CodePreviewType
+	MarkdownPreviewType
+	AudioPreviewType
+	VideoPreviewType
+	PDFPreviewType
 )
 
 //PreviewTemplate template struct for preview
@@ -30,6 +92,9 @@ type PreviewTemplate struct {
 	Host           string
 	FileSizeStr    string
 	Encrypted      bool
+	Content        string
+	Truncated      bool
+	FileType       string
 }
 
 //PreviewTypeFromMime get Type to preview from mime
@@ -38,14 +103,11 @@ func PreviewTypeFromMime(sMime string) PreviewType {
 		return DefaultPreviewType
 	}
 
-	for ptype, mimes := range PreviewMimes {
-		for _, mime := range mimes {
-			if strings.HasSuffix(mime, "*") {
-				if strings.HasPrefix(sMime, mime[:len(mime)-1]) {
-					return ptype
-				}
-			} else {
-				if mime == sMime {
+	// Exact mimes are more specific than wildcards
+	for _, wildcard := range []bool{false, true} {
+		for ptype, mimes := range PreviewMimes {
+			for _, mime := range mimes {
+				if strings.HasSuffix(mime, "*") == wildcard && matchMime(mime, sMime) {
 					return ptype
 				}
 			}
@@ -55,6 +117,29 @@ func PreviewTypeFromMime(sMime string) PreviewType {
 	return DefaultPreviewType
 }
 
+//PreviewTypeFromFile get Type to preview from mime and the extension of text files
+func PreviewTypeFromFile(sMime, name string) PreviewType {
+	ptype := PreviewTypeFromMime(sMime)
+	if ptype != TextPreviewType && ptype != CodePreviewType {
+		return ptype
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(ext) == 0 {
+		return ptype
+	}
+
+	for extType, extensions := range PreviewExtensions {
+		for _, extension := range extensions {
+			if ext == extension {
+				return extType
+			}
+		}
+	}
+
+	return ptype
+}
+
 //IsImagePreview return true if pt is image previewtype
 func IsImagePreview(pt PreviewType) bool {
 	return pt == ImagePreviewType
@@ -65,7 +150,58 @@ func IsTextPreview(pt PreviewType) bool {
 	return pt == TextPreviewType
 }
 
+//IsCodePreview return true if pt is code previewtype
+func IsCodePreview(pt PreviewType) bool {
+	return pt == CodePreviewType
+}
+
+//IsMarkdownPreview return true if pt is markdown previewtype
+func IsMarkdownPreview(pt PreviewType) bool {
+	return pt == MarkdownPreviewType
+}
+
+//IsAudioPreview return true if pt is audio previewtype
+func IsAudioPreview(pt PreviewType) bool {
+	return pt == AudioPreviewType
+}
+
+//IsVideoPreview return true if pt is video previewtype
+func IsVideoPreview(pt PreviewType) bool {
+	return pt == VideoPreviewType
+}
+
+//IsPDFPreview return true if pt is pdf previewtype
+func IsPDFPreview(pt PreviewType) bool {
+	return pt == PDFPreviewType
+}
+
 //IsDefaultPreview return true if pt is default previewtype
 func IsDefaultPreview(pt PreviewType) bool {
 	return pt == DefaultPreviewType
 }
+
+//IsRenderedPreview return true if the content of pt is rendered by the server
+func IsRenderedPreview(pt PreviewType) bool {
+	return pt == TextPreviewType || pt == CodePreviewType || pt == MarkdownPreviewType
+}
+
+//GalleryTemplate template struct for collection galleries
+type GalleryTemplate struct {
+	Title         string
+	PublicName    string
+	Host          string
+	Files         []GalleryFileItem
+	TotalSizeStr  string
+	NeedPassword  bool
+	WrongPassword bool
+}
+
+//GalleryFileItem a file in a gallery
+type GalleryFileItem struct {
+	ID           uint
+	Name         string
+	FileSizeStr  string
+	IsImage      bool
+	HasThumbnail bool
+	Encrypted    bool
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+//QuarantineInfo describes a quarantined file. It's stored next to its content
+type QuarantineInfo struct {
+	FileID    uint      `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	User      string    `json:"user"`
+	Namespace string    `json:"ns"`
+	Signature string    `json:"signature"`
+	Date      time.Time `json:"date"`
+
+	// Wrapped data key of files encrypted at rest
+	DataKey   []byte `json:"datakey,omitempty"`
+	DataKeyID string `json:"datakeyid,omitempty"`
+}
+
+//QuarantineLocalFile moves the content of the file into the quarantine
+func QuarantineLocalFile(config *Config, file *File, user *User, signature string) error {
+	info := QuarantineInfo{
+		FileID:    file.ID,
+		Name:      file.Name,
+		Signature: signature,
+		Date:      time.Now(),
+		DataKey:   file.DataKey,
+		DataKeyID: file.DataKeyID,
+	}
+	if user != nil {
+		info.User = user.Username
+	}
+	if file.Namespace != nil {
+		info.Namespace = file.Namespace.Name
+	}
+
+	// Write the info first, it holds the data key of encrypted files
+	quarantineFile := config.GetQuarantineFile(file.LocalName)
+	if err := info.Write(quarantineFile + ".json"); err != nil {
+		return err
+	}
+
+	if err := moveFile(config.GetStorageFile(file.LocalName), quarantineFile); err != nil {
+		os.Remove(quarantineFile + ".json")
+		return err
+	}
+
+	return nil
+}
+
+//ReadQuarantineInfo reads the info of a quarantined file
+func ReadQuarantineInfo(file string) (*QuarantineInfo, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var info QuarantineInfo
+	if err = json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+//Write stores the info. An existing info is replaced only if the new one was written completely
+func (info QuarantineInfo) Write(file string) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(file+".tmp", b, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(file+".tmp", file)
+}
+
+//Quarantine moves an infected file into the quarantine. It's unpublished and can't be downloaded anymore
+func (file *File) Quarantine(db *gorm.DB, config *Config, signature string) error {
+	if err := QuarantineLocalFile(config, file, file.User, signature); err != nil {
+		return err
+	}
+
+	err := db.Model(file).UpdateColumns(map[string]interface{}{
+		"quarantined":   true,
+		"signature":     signature,
+		"is_public":     false,
+		"has_thumbnail": false,
+	}).Error
+	if err != nil {
+		// Move the content back, the file is still served
+		quarantineFile := config.GetQuarantineFile(file.LocalName)
+		if moveErr := moveFile(quarantineFile, config.GetStorageFile(file.LocalName)); moveErr == nil {
+			os.Remove(quarantineFile + ".json")
+		}
+		return err
+	}
+
+	RemoveThumbnails(config, file.LocalName)
+
+	file.Quarantined = true
+	file.Signature = signature
+	file.IsPublic = false
+	file.HasThumbnail = false
+	return nil
+}
+
+//ShredQuarantinedFile shreds and removes a quarantined file and its info
+func ShredQuarantinedFile(config *Config, localName string) {
+	quarantineFile := config.GetQuarantineFile(localName)
+	shredFile(quarantineFile)
+
+	if err := os.Remove(quarantineFile + ".json"); err != nil {
+		log.Warn(err)
+	}
+}
+
+//Move a file. Files on other filesystems are copied and removed afterwards
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if linkErr, ok := err.(*os.LinkError); !ok || linkErr.Err != syscall.EXDEV {
+		return err
+	}
+
+	if err = copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+//Copy the content of src to a new file dst and sync it to disk
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
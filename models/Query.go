@@ -0,0 +1,480 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+)
+
+//ErrEmptyQuery error if a query has no terms
+var ErrEmptyQuery = errors.New("empty query")
+
+//FileQuery a parsed file query like `tag:release AND NOT tag:draft size>10MB`
+type FileQuery struct {
+	root queryNode
+}
+
+//A node of a parsed query
+type queryNode interface {
+	//Return the SQL condition and its arguments
+	toSQL() (string, []interface{})
+}
+
+type queryAnd struct {
+	left, right queryNode
+}
+
+type queryOr struct {
+	left, right queryNode
+}
+
+type queryNot struct {
+	node queryNode
+}
+
+type queryCondition struct {
+	sql  string
+	args []interface{}
+}
+
+func (n queryAnd) toSQL() (string, []interface{}) {
+	lSQL, lArgs := n.left.toSQL()
+	rSQL, rArgs := n.right.toSQL()
+	return "(" + lSQL + " AND " + rSQL + ")", append(lArgs, rArgs...)
+}
+
+func (n queryOr) toSQL() (string, []interface{}) {
+	lSQL, lArgs := n.left.toSQL()
+	rSQL, rArgs := n.right.toSQL()
+	return "(" + lSQL + " OR " + rSQL + ")", append(lArgs, rArgs...)
+}
+
+func (n queryNot) toSQL() (string, []interface{}) {
+	sql, args := n.node.toSQL()
+	return "NOT " + sql, args
+}
+
+func (n queryCondition) toSQL() (string, []interface{}) {
+	return "(" + n.sql + ")", n.args
+}
+
+//ParseFileQuery parses a file query
+func ParseFileQuery(query string) (*FileQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	parser := queryParser{
+		tokens: tokens,
+	}
+
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !parser.done() {
+		return nil, fmt.Errorf("unexpected '%s'", parser.peek())
+	}
+
+	return &FileQuery{
+		root: root,
+	}, nil
+}
+
+//Apply adds the query as condition to db
+func (query *FileQuery) Apply(db *gorm.DB) *gorm.DB {
+	if query == nil {
+		return db
+	}
+
+	sql, args := query.root.toSQL()
+	return db.Where(sql, args...)
+}
+
+// -------- Tokenizer
+
+//Split the query into words and parentheses. Quotes group whitespaces
+func tokenizeQuery(query string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	var inQuotes bool
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, c := range query {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteRune(c)
+		case unicode.IsSpace(c):
+			flush()
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			current.WriteRune(c)
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+
+	flush()
+	return tokens, nil
+}
+
+// -------- Parser
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (parser *queryParser) done() bool {
+	return parser.pos >= len(parser.tokens)
+}
+
+func (parser *queryParser) peek() string {
+	if parser.done() {
+		return ""
+	}
+	return parser.tokens[parser.pos]
+}
+
+func (parser *queryParser) next() string {
+	token := parser.peek()
+	parser.pos++
+	return token
+}
+
+// or := and ("OR" and)*
+func (parser *queryParser) parseOr() (queryNode, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.peek() == "OR" {
+		parser.next()
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = queryOr{left, right}
+	}
+
+	return left, nil
+}
+
+// and := unary (["AND"] unary)*
+func (parser *queryParser) parseAnd() (queryNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for !parser.done() && parser.peek() != "OR" && parser.peek() != ")" {
+		if parser.peek() == "AND" {
+			parser.next()
+		}
+
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = queryAnd{left, right}
+	}
+
+	return left, nil
+}
+
+// unary := "NOT" unary | "(" or ")" | term
+func (parser *queryParser) parseUnary() (queryNode, error) {
+	switch token := parser.next(); token {
+	case "":
+		return nil, errors.New("unexpected end of query")
+	case "NOT":
+		node, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{node}, nil
+	case "(":
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if parser.next() != ")" {
+			return nil, errors.New("missing ')'")
+		}
+		return node, nil
+	case ")", "AND", "OR":
+		return nil, fmt.Errorf("unexpected '%s'", token)
+	default:
+		// -term is short for NOT term
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			node, err := parseQueryTerm(token[1:])
+			if err != nil {
+				return nil, err
+			}
+			return queryNot{node}, nil
+		}
+
+		return parseQueryTerm(token)
+	}
+}
+
+// -------- Terms
+
+var termRegex = regexp.MustCompile(`^([a-zA-Z]+)(\.[a-zA-Z0-9_\-]+)?:?(>=|<=|>|<|=)?(.*)$`)
+
+//Parse a single term like size>10MB or tag:release
+func parseQueryTerm(term string) (queryNode, error) {
+	// Terms without key search the name
+	if !strings.ContainsAny(term, ":<>=") {
+		return queryCondition{
+			sql:  "files.name LIKE ?",
+			args: []interface{}{"%" + escapeLike(term) + "%"},
+		}, nil
+	}
+
+	match := termRegex.FindStringSubmatch(term)
+	if match == nil {
+		return nil, fmt.Errorf("invalid term '%s'", term)
+	}
+
+	key, operator, value := strings.ToLower(match[1]), match[3], match[4]
+	if len(value) == 0 {
+		return nil, fmt.Errorf("missing value for '%s'", key)
+	}
+
+	if len(operator) == 0 {
+		operator = "="
+	}
+
+	// Custom metadata field like meta.build
+	if len(match[2]) > 0 {
+		if key != "meta" {
+			return nil, fmt.Errorf("unknown key '%s%s'", key, match[2])
+		}
+		return metaCondition(match[2][1:], operator, value)
+	}
+
+	return buildQueryCondition(key, operator, value)
+}
+
+func buildQueryCondition(key, operator, value string) (queryNode, error) {
+	switch key {
+	case "name":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return likeCondition("files.name", value), nil
+	case "desc", "description":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return likeCondition("files.description", value), nil
+	case "type", "mime":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return likeCondition("files.file_type", value), nil
+	case "tag":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return queryCondition{
+			sql: `files.id IN (SELECT files_tags.file_id FROM files_tags
+				INNER JOIN tags ON tags.id = files_tags.tag_id
+				WHERE (tags.name LIKE ? OR tags.name LIKE ?) AND tags.deleted_at IS NULL)`,
+			args: []interface{}{globToLike(value), globToLike(value) + TagSeparator + "%"},
+		}, nil
+	case "group":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return queryCondition{
+			sql: `files.id IN (SELECT files_groups.file_id FROM files_groups
+				INNER JOIN "groups" ON "groups".id = files_groups.group_id
+				WHERE "groups".name LIKE ? AND "groups".deleted_at IS NULL)`,
+			args: []interface{}{globToLike(value)},
+		}, nil
+	case "ns", "namespace":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		return queryCondition{
+			sql:  "files.namespace_id IN (SELECT id FROM namespaces WHERE name LIKE ? AND deleted_at IS NULL)",
+			args: []interface{}{globToLike(value)},
+		}, nil
+	case "public", "encrypted":
+		if err := requireEqualOperator(key, operator); err != nil {
+			return nil, err
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a bool", key)
+		}
+		if key == "public" {
+			return queryCondition{"files.is_public = ?", []interface{}{b}}, nil
+		}
+		if b {
+			return queryCondition{"files.encryption IS NOT NULL", nil}, nil
+		}
+		return queryCondition{"files.encryption IS NULL", nil}, nil
+	case "size":
+		size, err := ParseSize(value)
+		if err != nil {
+			return nil, err
+		}
+		return queryCondition{"files.file_size " + operator + " ?", []interface{}{size}}, nil
+	case "id":
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, errors.New("id must be a number")
+		}
+		return queryCondition{"files.id " + operator + " ?", []interface{}{id}}, nil
+	case "created", "updated", "expires":
+		column := map[string]string{
+			"created": "files.created_at",
+			"updated": "files.updated_at",
+			"expires": "files.expires_at",
+		}[key]
+		return dateCondition(column, operator, value)
+	}
+
+	return nil, fmt.Errorf("unknown key '%s'", key)
+}
+
+//Compare metadata. Numbers are compared numerically
+func metaCondition(key, operator, value string) (queryNode, error) {
+	if operator == "=" {
+		condition := likeCondition("value", value).(queryCondition)
+		return queryCondition{
+			sql:  "files.id IN (SELECT file_id FROM file_meta WHERE key = ? AND " + condition.sql + ")",
+			args: append([]interface{}{key}, condition.args...),
+		}, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("meta.%s can only be compared with numbers", key)
+	}
+
+	return queryCondition{
+		sql: `files.id IN (SELECT file_id FROM file_meta WHERE key = ?
+			AND CASE WHEN type IN (?, ?) THEN CAST(value AS double precision) END ` + operator + ` ?)`,
+		args: []interface{}{key, IntMetaType, FloatMetaType, number},
+	}, nil
+}
+
+func requireEqualOperator(key, operator string) error {
+	if operator != "=" {
+		return fmt.Errorf("'%s' can't be compared with %s", key, operator)
+	}
+	return nil
+}
+
+//Use LIKE if value contains wildcards. Otherwise compare exact
+func likeCondition(column, value string) queryNode {
+	if strings.Contains(value, "*") {
+		return queryCondition{column + " LIKE ?", []interface{}{globToLike(value)}}
+	}
+	return queryCondition{column + " = ?", []interface{}{value}}
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func dateCondition(column, operator, value string) (queryNode, error) {
+	for _, layout := range dateLayouts {
+		date, err := time.ParseInLocation(layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+
+		// A day matches the whole day
+		if operator == "=" && layout == "2006-01-02" {
+			return queryCondition{
+				column + " >= ? AND " + column + " < ?",
+				[]interface{}{date, date.AddDate(0, 0, 1)},
+			}, nil
+		}
+
+		return queryCondition{column + " " + operator + " ?", []interface{}{date}}, nil
+	}
+
+	return nil, fmt.Errorf("invalid date '%s'", value)
+}
+
+var sizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+//ParseSize parses a size like 10MB or 1.5GiB into bytes
+func ParseSize(size string) (int64, error) {
+	match := sizeRegex.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit '%s'", match[2])
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(value * unit), nil
+}
+
+//Escape the special chars of LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+//Convert a glob with * into a LIKE pattern
+func globToLike(glob string) string {
+	return strings.ReplaceAll(escapeLike(glob), "*", "%")
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+//SQL of a search in the file name
+func nameSQL() string {
+	return "(files.name LIKE ?)"
+}
+
+func TestParseFileQuery(t *testing.T) {
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name  string
+		query string
+		sql   string
+		args  []interface{}
+	}{
+		// Names
+		{"word", "report", nameSQL(), []interface{}{"%report%"}},
+		{"like chars are escaped", `50%_off\`, nameSQL(), []interface{}{`%50\%\_off\\%`}},
+		{"quotes group whitespaces", `"annual report"`, nameSQL(), []interface{}{"%annual report%"}},
+		{"quoted value", `name:"my file.txt"`, "(files.name = ?)", []interface{}{"my file.txt"}},
+		{"quoted operators", `"a OR b"`, nameSQL(), []interface{}{"%a OR b%"}},
+		{"glob", "name:*.png", "(files.name LIKE ?)", []interface{}{"%.png"}},
+		{"glob escapes like chars", "name:100%*", "(files.name LIKE ?)", []interface{}{`100\%%`}},
+		{"keys are case insensitive", "NAME:a", "(files.name = ?)", []interface{}{"a"}},
+		{"sql is a value", "name:x';DROP", "(files.name = ?)", []interface{}{"x';DROP"}},
+
+		// Precedence
+		{"implicit and", "a b", "(" + nameSQL() + " AND " + nameSQL() + ")", []interface{}{"%a%", "%b%"}},
+		{"explicit and", "a AND b", "(" + nameSQL() + " AND " + nameSQL() + ")", []interface{}{"%a%", "%b%"}},
+		{"and before or", "a b OR c", "((" + nameSQL() + " AND " + nameSQL() + ") OR " + nameSQL() + ")", []interface{}{"%a%", "%b%", "%c%"}},
+		{"or after and", "a OR b c", "(" + nameSQL() + " OR (" + nameSQL() + " AND " + nameSQL() + "))", []interface{}{"%a%", "%b%", "%c%"}},
+		{"or is left associative", "a OR b OR c", "((" + nameSQL() + " OR " + nameSQL() + ") OR " + nameSQL() + ")", []interface{}{"%a%", "%b%", "%c%"}},
+		{"parentheses", "(a OR b) c", "((" + nameSQL() + " OR " + nameSQL() + ") AND " + nameSQL() + ")", []interface{}{"%a%", "%b%", "%c%"}},
+		{"not binds tighter than and", "NOT a b", "(NOT " + nameSQL() + " AND " + nameSQL() + ")", []interface{}{"%a%", "%b%"}},
+		{"not group", "NOT (a OR b)", "NOT (" + nameSQL() + " OR " + nameSQL() + ")", []interface{}{"%a%", "%b%"}},
+		{"minus is not", "-a", "NOT " + nameSQL(), []interface{}{"%a%"}},
+		{"lowercase operators are words", "a or b", "((" + nameSQL() + " AND " + nameSQL() + ") AND " + nameSQL() + ")", []interface{}{"%a%", "%or%", "%b%"}},
+
+		// Fields
+		{"description", "desc:*draft*", "(files.description LIKE ?)", []interface{}{"%draft%"}},
+		{"type", "type:image/*", "(files.file_type LIKE ?)", []interface{}{"image/%"}},
+		{"tag matches children", "tag:release", "(files.id IN (SELECT files_tags.file_id FROM files_tags INNER JOIN tags ON tags.id = files_tags.tag_id WHERE (tags.name LIKE ? OR tags.name LIKE ?) AND tags.deleted_at IS NULL))", []interface{}{"release", "release/%"}},
+		{"group", "group:team_*", `(files.id IN (SELECT files_groups.file_id FROM files_groups INNER JOIN "groups" ON "groups".id = files_groups.group_id WHERE "groups".name LIKE ? AND "groups".deleted_at IS NULL))`, []interface{}{`team\_%`}},
+		{"namespace", "ns:default", "(files.namespace_id IN (SELECT id FROM namespaces WHERE name LIKE ? AND deleted_at IS NULL))", []interface{}{"default"}},
+		{"public", "public:true", "(files.is_public = ?)", []interface{}{true}},
+		{"encrypted", "encrypted:false", "(files.encryption IS NULL)", nil},
+		{"size", "size>10MB", "(files.file_size > ?)", []interface{}{int64(10e6)}},
+		{"size binary unit", "size<=1.5KiB", "(files.file_size <= ?)", []interface{}{int64(1536)}},
+		{"id", "id=5", "(files.id = ?)", []interface{}{uint64(5)}},
+		{"day", "created:2020-01-02", "(files.created_at >= ? AND files.created_at < ?)", []interface{}{day, day.AddDate(0, 0, 1)}},
+		{"date", "expires<2020-01-02", "(files.expires_at < ?)", []interface{}{day}},
+		{"meta", "meta.build:42", "(files.id IN (SELECT file_id FROM file_meta WHERE key = ? AND value = ?))", []interface{}{"build", "42"}},
+		{"meta number", "meta.build>=42", "(files.id IN (SELECT file_id FROM file_meta WHERE key = ? AND CASE WHEN type IN (?, ?) THEN CAST(value AS double precision) END >= ?))", []interface{}{"build", IntMetaType, FloatMetaType, float64(42)}},
+	}
+
+	for _, test := range tests {
+		query, err := ParseFileQuery(test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		sql, args := query.root.toSQL()
+		if sql = strings.Join(strings.Fields(sql), " "); sql != test.sql {
+			t.Errorf("%s: expected SQL\n%s\ngot\n%s", test.name, test.sql, sql)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%s: expected args %v, got %v", test.name, test.args, args)
+		}
+	}
+}
+
+func TestParseFileQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query"},
+		{`"unterminated`, "unterminated quote"},
+		{"(a", "missing ')'"},
+		{"a)", "unexpected ')'"},
+		{"()", "unexpected ')'"},
+		{"AND a", "unexpected 'AND'"},
+		{"a OR", "unexpected end of query"},
+		{"a AND", "unexpected end of query"},
+		{"NOT", "unexpected end of query"},
+		{"a OR OR b", "unexpected 'OR'"},
+		{"foo:bar", "unknown key 'foo'"},
+		{"other.build:1", "unknown key 'other.build'"},
+		{"1=1", "invalid term '1=1'"},
+		{"name:", "missing value for 'name'"},
+		{"name>a", "'name' can't be compared with >"},
+		{"tag<=a", "'tag' can't be compared with <="},
+		{"public:maybe", "public must be a bool"},
+		{"size>big", "invalid size 'big'"},
+		{"size>10XB", "invalid size unit 'XB'"},
+		{"id:x", "id must be a number"},
+		{"created>yesterday", "invalid date 'yesterday'"},
+		{"meta.build>new", "meta.build can only be compared with numbers"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFileQuery(test.query)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: expected error %q, got %v", test.query, test.err, err)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":      0,
+		"512":    512,
+		"10b":    10,
+		"1k":     1000,
+		"1.5MB":  1500000,
+		"2 GB":   2e9,
+		"1TB":    1e12,
+		"1KiB":   1024,
+		"3MiB":   3 << 20,
+		"1GiB":   1 << 30,
+		" 1TiB ": 1 << 40,
+	}
+
+	for size, expected := range tests {
+		if got, err := ParseSize(size); err != nil || got != expected {
+			t.Errorf("%q: expected %d, got %d %v", size, expected, got, err)
+		}
+	}
+
+	for _, size := range []string{"", "-1", "1.", "MB", "1 2", "1PB"} {
+		if _, err := ParseSize(size); err == nil {
+			t.Errorf("%q: expected error", size)
+		}
+	}
+}
+
+func TestFileQueryApply(t *testing.T) {
+	db := newTestDB(t)
+	user, namespace := newTestNamespace(t, db, "user_test")
+
+	files := []*File{
+		{Name: "report.pdf", FileType: "application/pdf", FileSize: 2e6},
+		{Name: "photo.png", FileType: "image/png", FileSize: 5e5, IsPublic: true},
+		{Name: "draft.png", FileType: "image/png", FileSize: 3e6},
+	}
+	for _, file := range files {
+		file.LocalName = file.Name
+		file.Namespace = namespace
+		file.NamespaceID = namespace.ID
+		file.Tags = []Tag{*GetTag(db, "release", namespace, user)}
+		if file.Name == "draft.png" {
+			file.Tags = []Tag{*GetTag(db, "release/beta", namespace, user)}
+		}
+
+		if err := file.Insert(db, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := files[0].SetMeta(db, map[string]interface{}{"pages": 12}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		names []string
+	}{
+		{"type:image/*", []string{"draft.png", "photo.png"}},
+		{"type:image/* size>1MB", []string{"draft.png"}},
+		{"public:true OR size>=2MB", []string{"draft.png", "photo.png", "report.pdf"}},
+		{"tag:release -tag:release/beta", []string{"photo.png", "report.pdf"}},
+		{"NOT (name:*.png)", []string{"report.pdf"}},
+		{"meta.pages>10", []string{"report.pdf"}},
+		{"meta.pages<10", nil},
+		{`"raft"`, []string{"draft.png"}},
+	}
+
+	for _, test := range tests {
+		query, err := ParseFileQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		if err = query.Apply(db.Model(&File{})).Pluck("files.name", &names).Error; err != nil {
+			t.Errorf("%s: %v", test.query, err)
+			continue
+		}
+
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: expected %v, got %v", test.query, test.names, names)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // PingRequest ping request
 type PingRequest struct {
 	Payload string
@@ -19,24 +21,52 @@ type FileRequest struct {
 	Updates    FileUpdateItem `json:"updates,omitempty"`
 	All        bool           `json:"all"`
 	Attributes FileAttributes `json:"attributes"`
+	Archive    string         `json:"archive,omitempty"`
+	Query      string         `json:"query,omitempty"`
+	Thumbnail  string         `json:"thumb,omitempty"`
 }
 
 // NamespaceRequest namespace action request
 type NamespaceRequest struct {
-	Namespace string        `json:"ns"`
-	NewName   string        `json:"newName,omitempty"`
-	Type      NamespaceType `json:"nstype"`
+	Namespace string          `json:"ns"`
+	NewName   string          `json:"newName,omitempty"`
+	Type      NamespaceType   `json:"nstype"`
+	Settings  json.RawMessage `json:"settings,omitempty"`
+	MoveTo    string          `json:"moveTo,omitempty"`
+}
+
+// CollectionRequest request to publish or delete a collection
+type CollectionRequest struct {
+	Namespace  string `json:"ns"`
+	Type       string `json:"type"`
+	Name       string `json:"name,omitempty"`
+	PublicName string `json:"pubname,omitempty"`
+	Expiry     string `json:"expiry,omitempty"`
+	Password   string `json:"pass,omitempty"`
+}
+
+// ExportRequest request to export a namespace
+type ExportRequest struct {
+	Namespace string   `json:"ns"`
+	Format    string   `json:"format,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
 }
 
 // FileUpdateItem lists changes to a file
 type FileUpdateItem struct {
-	IsPublic     string   `json:"ispublic,omitempty"`
-	NewName      string   `json:"name,omitempty"`
-	NewNamespace string   `json:"namespace,omitempty"`
-	RemoveTags   []string `json:"rem_tags,omitempty"`
-	RemoveGroups []string `json:"rem_groups,omitempty"`
-	AddTags      []string `json:"add_tags,omitempty"`
-	AddGroups    []string `json:"add_groups,omitempty"`
+	IsPublic     string                 `json:"ispublic,omitempty"`
+	NewName      string                 `json:"name,omitempty"`
+	NewNamespace string                 `json:"namespace,omitempty"`
+	RemoveTags   []string               `json:"rem_tags,omitempty"`
+	RemoveGroups []string               `json:"rem_groups,omitempty"`
+	AddTags      []string               `json:"add_tags,omitempty"`
+	AddGroups    []string               `json:"add_groups,omitempty"`
+	Description  *string                `json:"desc,omitempty"`
+	SetMeta      map[string]interface{} `json:"set_meta,omitempty"`
+	RemoveMeta   []string               `json:"rem_meta,omitempty"`
+	Refresh      *string                `json:"refresh,omitempty"`
 }
 
 // UpdateAttributeRequest contains data to update a tag
@@ -44,6 +74,21 @@ type UpdateAttributeRequest struct {
 	Name      string `json:"name"`
 	NewName   string `json:"newname"`
 	Namespace string `json:"namespace"`
+	Merge     bool   `json:"merge,omitempty"`
+}
+
+// RuleRequest request to manage rules of a namespace
+type RuleRequest struct {
+	Namespace string        `json:"ns"`
+	Rule      *RuleSettings `json:"rule,omitempty"`
+}
+
+// AttributeListRequest request to list tags or groups
+type AttributeListRequest struct {
+	Namespace     string `json:"ns"`
+	AllNamespaces bool   `json:"allns"`
+	Prefix        string `json:"prefix,omitempty"`
+	Limit         uint   `json:"limit,omitempty"`
 }
 
 // FileListRequest contains file info (and a file)
@@ -53,23 +98,38 @@ type FileListRequest struct {
 	AllNamespaces  bool                     `json:"allns"`
 	OptionalParams OptionalRequetsParameter `json:"opt"`
 	Attributes     FileAttributes           `json:"attributes"`
+	Query          string                   `json:"query,omitempty"`
 }
 
 //OptionalRequetsParameter optional request parameter
 type OptionalRequetsParameter struct {
-	Verbose uint8 `json:"verb"`
+	Verbose uint8  `json:"verb"`
+	Limit   uint   `json:"limit,omitempty"`
+	Cursor  string `json:"cursor,omitempty"`
+	OrderBy string `json:"order,omitempty"`
+	Desc    bool   `json:"desc,omitempty"`
+	Total   bool   `json:"total,omitempty"`
+}
+
+// RescanRequest selects files to scan for malware again
+type RescanRequest struct {
+	Files     []uint `json:"files,omitempty"`
+	Namespace string `json:"ns,omitempty"`
+	All       bool   `json:"all,omitempty"`
 }
 
 // UploadRequest contains file info (and a file)
 type UploadRequest struct {
-	UploadType  UploadType     `json:"type"`
-	URL         string         `json:"url,omitempty"`
-	Name        string         `json:"name"`
-	Public      bool           `json:"pb,omitempty"`
-	PublicName  string         `json:"pbname,omitempty"`
-	Attributes  FileAttributes `json:"attr,omitempty"`
-	Encryption  string         `json:"e,omitempty"`
-	ReplaceFile uint           `json:"r,omitempty"`
+	UploadType  UploadType        `json:"type"`
+	URL         string            `json:"url,omitempty"`
+	Name        string            `json:"name"`
+	Public      bool              `json:"pb,omitempty"`
+	PublicName  string            `json:"pbname,omitempty"`
+	Attributes  FileAttributes    `json:"attr,omitempty"`
+	Encryption  string            `json:"e,omitempty"`
+	EncParams   map[string]string `json:"ep,omitempty"`
+	ReplaceFile uint              `json:"r,omitempty"`
+	Refresh     string            `json:"refresh,omitempty"`
 }
 
 //UploadType type of upload
@@ -80,3 +140,11 @@ const (
 	FileUploadType UploadType = iota
 	URLUploadType
 )
+
+// SearchRequest full-text search request
+type SearchRequest struct {
+	Query         string `json:"query"`
+	Namespace     string `json:"ns"`
+	AllNamespaces bool   `json:"allns"`
+	Limit         uint   `json:"limit,omitempty"`
+}
@@ -48,6 +48,8 @@ const (
 	HeaderFileName string = "X-Filename"
 	//HeaderEncryption encryption header
 	HeaderEncryption string = "X-Encryption"
+	//HeaderEncryptionParams parameters of the encryption cipher, url encoded
+	HeaderEncryptionParams string = "X-Encryption-Params"
 	//HeaderRequest request content
 	HeaderRequest string = "Request"
 )
@@ -64,14 +66,98 @@ type StringSliceResponse struct {
 
 //FileResponseItem file item for file response
 type FileResponseItem struct {
-	ID           uint           `json:"id"`
-	Size         int64          `json:"size"`
-	CreationDate time.Time      `json:"creation"`
-	Name         string         `json:"name"`
-	PublicName   string         `json:"pubname"`
-	IsPublic     bool           `json:"isPub"`
-	Attributes   FileAttributes `json:"attrib"`
-	Encryption   string         `json:"e"`
+	ID           uint              `json:"id"`
+	Size         int64             `json:"size"`
+	CreationDate time.Time         `json:"creation"`
+	Name         string            `json:"name"`
+	PublicName   string            `json:"pubname"`
+	IsPublic     bool              `json:"isPub"`
+	Attributes   FileAttributes    `json:"attrib"`
+	Encryption   string            `json:"e"`
+	EncParams    map[string]string `json:"ep,omitempty"`
+	Expiry       *time.Time        `json:"expiry,omitempty"`
+	Description  string            `json:"desc,omitempty"`
+	Thumbnail    bool              `json:"thumb,omitempty"`
+	Mirror       string            `json:"mirror,omitempty"`
+	Refresh      string            `json:"refresh,omitempty"`
+	Infected     string            `json:"infected,omitempty"`
+}
+
+//OEmbedResponse oEmbed data of a public file
+type OEmbedResponse struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	URL             string `json:"url,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+}
+
+//AttributeResponseItem a tag or group with its usage
+type AttributeResponseItem struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"ns"`
+	Files     int64      `json:"files"`
+	Size      int64      `json:"size"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+}
+
+//AttributeListResponse response for listing tags or groups
+type AttributeListResponse struct {
+	Attributes []AttributeResponseItem `json:"attributes"`
+}
+
+//TagTreeItem a nested tag with its children
+type TagTreeItem struct {
+	Name      string        `json:"name"`
+	Path      string        `json:"path"`
+	Namespace string        `json:"ns"`
+	Files     int64         `json:"files"`
+	Size      int64         `json:"size"`
+	LastUsed  *time.Time    `json:"lastUsed,omitempty"`
+	Children  []TagTreeItem `json:"children,omitempty"`
+}
+
+//TagTreeResponse response for the tag hierarchy
+type TagTreeResponse struct {
+	Tags []TagTreeItem `json:"tags"`
+}
+
+//RuleListResponse response for listing rules of a namespace
+type RuleListResponse struct {
+	Rules []RuleSettings `json:"rules"`
+}
+
+//AttributeRenameItem a renamed tag or group
+type AttributeRenameItem struct {
+	Name    string `json:"name"`
+	NewName string `json:"newname"`
+	Merged  bool   `json:"merged,omitempty"`
+}
+
+//AttributeRenameResponse response for renaming tags or groups
+type AttributeRenameResponse struct {
+	Renamed []AttributeRenameItem `json:"renamed"`
+}
+
+//CollectionResponseItem a public collection
+type CollectionResponseItem struct {
+	PublicName string     `json:"pubname"`
+	Type       string     `json:"type"`
+	Namespace  string     `json:"ns"`
+	Name       string     `json:"name,omitempty"`
+	Expiry     *time.Time `json:"expiry,omitempty"`
+	Protected  bool       `json:"protected,omitempty"`
+}
+
+//CollectionListResponse response for listing collections
+type CollectionListResponse struct {
+	Collections []CollectionResponseItem `json:"collections"`
 }
 
 //PublishResponse response for publishing a file
@@ -86,7 +172,15 @@ type BulkPublishResponse struct {
 
 //ListFileResponse response for list files
 type ListFileResponse struct {
-	Files []FileResponseItem `json:"files"`
+	Files      []FileResponseItem `json:"files"`
+	NextCursor string             `json:"cursor,omitempty"`
+	Truncated  bool               `json:"truncated,omitempty"`
+	Total      *int64             `json:"total,omitempty"`
+}
+
+//ImportResponse response for importing an archive
+type ImportResponse struct {
+	Files []UploadResponse `json:"files"`
 }
 
 //UploadResponse response for uploading file
@@ -106,3 +200,27 @@ type LoginResponse struct {
 type CountResponse struct {
 	Count uint32 `json:"count"`
 }
+
+//JobResponse response containing the state of a job
+type JobResponse struct {
+	ID       uint      `json:"id"`
+	Type     string    `json:"type"`
+	State    string    `json:"state"`
+	Progress int64     `json:"progress"`
+	Total    int64     `json:"total"`
+	Message  string    `json:"msg,omitempty"`
+	FileID   uint      `json:"file,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+//SearchResultItem a file found by a full-text search
+type SearchResultItem struct {
+	FileResponseItem
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+//SearchResponse response for a full-text search
+type SearchResponse struct {
+	Results []SearchResultItem `json:"results"`
+}
@@ -8,8 +8,17 @@ type Role struct {
 	AccesForeignNamespaces Permission `gorm:"type:smallint"`
 	MaxURLcontentSize      int64
 	MaxUploadFileSize      int64
+	URLConnectTimeout      int
+	URLTimeout             int
 	CreateCustomNamespaces bool
 	CreateUserNamespaces   bool
+
+	// Comma separated mime types (image/*) or extensions (.exe). Empty allow lists allow all types
+	AllowedUploadTypes  string
+	DeniedUploadTypes   string
+	AllowedPublishTypes string
+	DeniedPublishTypes  string
+	DeniedInlineTypes   string
 }
 
 //Permission permission for roles
@@ -22,6 +31,21 @@ const (
 	Writepermission
 )
 
+//IsUploadAllowed return true if the role can upload files of the given mime type and name
+func (role Role) IsUploadAllowed(mime, name string) bool {
+	return isTypeAllowed(role.AllowedUploadTypes, role.DeniedUploadTypes, mime, name)
+}
+
+//IsPublishAllowed return true if the role can publish files of the given mime type and name
+func (role Role) IsPublishAllowed(mime, name string) bool {
+	return isTypeAllowed(role.AllowedPublishTypes, role.DeniedPublishTypes, mime, name)
+}
+
+//IsInlineAllowed return true if public files of the role with the given mime type and name can be displayed by browsers
+func (role Role) IsInlineAllowed(mime, name string) bool {
+	return isTypeAllowed("", role.DeniedInlineTypes, mime, name)
+}
+
 //HasUploadLimit gets upload limit
 func (user User) HasUploadLimit() bool {
 	return user.Role.MaxURLcontentSize > -1
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//ErrEmptyRule error if a rule has nothing to apply
+var ErrEmptyRule = errors.New("rule needs tags, groups or an expiry")
+
+//Rule assigns tags, groups and an expiry to matching files of a namespace
+type Rule struct {
+	gorm.Model
+	NamespaceID uint       `sql:"index" gorm:"not null"`
+	Namespace   *Namespace `gorm:"association_autoupdate:false;association_autocreate:false"`
+	Mime        string
+	Pattern     string
+	MinSize     int64
+	MaxSize     int64
+	Tags        string
+	Groups      string
+	Expiry      time.Duration
+}
+
+//RuleSettings settings of a rule
+type RuleSettings struct {
+	ID      uint     `json:"id,omitempty"`
+	Mime    string   `json:"mime,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	MinSize string   `json:"minsize,omitempty"`
+	MaxSize string   `json:"maxsize,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	Expiry  string   `json:"expiry,omitempty"`
+}
+
+//FindRules returns all rules of a namespace
+func FindRules(db *gorm.DB, namespace *Namespace) ([]Rule, error) {
+	var rules []Rule
+	err := db.Where("namespace_id = ?", namespace.ID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+//FindRule finds a rule of a namespace
+func FindRule(db *gorm.DB, id uint, namespace *Namespace) (*Rule, error) {
+	var rule Rule
+	err := db.Where("id = ? AND namespace_id = ?", id, namespace.ID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+//GetSettings return the settings of the rule
+func (rule Rule) GetSettings() RuleSettings {
+	settings := RuleSettings{
+		ID:     rule.ID,
+		Mime:   rule.Mime,
+		Name:   rule.Pattern,
+		Tags:   splitList(rule.Tags),
+		Groups: splitList(rule.Groups),
+	}
+
+	if rule.MinSize > 0 {
+		settings.MinSize = fmt.Sprint(rule.MinSize)
+	}
+	if rule.MaxSize > 0 {
+		settings.MaxSize = fmt.Sprint(rule.MaxSize)
+	}
+	if rule.Expiry > 0 {
+		settings.Expiry = rule.Expiry.String()
+	}
+
+	return settings
+}
+
+//ApplySettings applies settings to the rule. Doesn't save it
+func (rule *Rule) ApplySettings(settings RuleSettings) error {
+	var err error
+	var minSize, maxSize int64
+	if len(settings.MinSize) > 0 {
+		if minSize, err = ParseSize(settings.MinSize); err != nil {
+			return err
+		}
+	}
+	if len(settings.MaxSize) > 0 {
+		if maxSize, err = ParseSize(settings.MaxSize); err != nil {
+			return err
+		}
+	}
+
+	expiry, err := ParseExpiry(settings.Expiry)
+	if err != nil {
+		return err
+	}
+
+	// Validate name pattern
+	if _, err = path.Match(settings.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern '%s'", settings.Name)
+	}
+
+	for i := range settings.Tags {
+		settings.Tags[i] = NormalizeTagName(settings.Tags[i])
+	}
+
+	rule.Mime = strings.TrimSpace(settings.Mime)
+	rule.Pattern = settings.Name
+	rule.MinSize = minSize
+	rule.MaxSize = maxSize
+	rule.Tags = joinList(settings.Tags)
+	rule.Groups = joinList(settings.Groups)
+	rule.Expiry = expiry
+
+	if len(rule.Tags) == 0 && len(rule.Groups) == 0 && rule.Expiry == 0 {
+		return ErrEmptyRule
+	}
+
+	return nil
+}
+
+//Matches return true if the rule applies to the file
+func (rule Rule) Matches(file File) bool {
+	if len(rule.Mime) > 0 && !matchMime(rule.Mime, file.FileType) {
+		return false
+	}
+
+	if len(rule.Pattern) > 0 {
+		if ok, _ := path.Match(rule.Pattern, file.Name); !ok {
+			return false
+		}
+	}
+
+	if (rule.MinSize > 0 && file.FileSize < rule.MinSize) ||
+		(rule.MaxSize > 0 && file.FileSize > rule.MaxSize) {
+		return false
+	}
+
+	return true
+}
+
+//ApplyRules adds the tags, groups and expiry of all matching rules to the file. Doesn't save it.
+//An expiry is only set if the file has none. Return true if the file was changed
+func (file *File) ApplyRules(db *gorm.DB, rules []Rule, user *User) bool {
+	var changed bool
+
+	for _, rule := range rules {
+		if !rule.Matches(*file) {
+			continue
+		}
+
+		for _, tag := range splitList(rule.Tags) {
+			if !file.HasTag(tag) {
+				file.Tags = append(file.Tags, *GetTag(db, tag, file.Namespace, user))
+				changed = true
+			}
+		}
+
+		for _, group := range splitList(rule.Groups) {
+			if !file.HasGroup(group) {
+				file.Groups = append(file.Groups, *GetGroup(db, group, file.Namespace, user))
+				changed = true
+			}
+		}
+
+		if rule.Expiry > 0 && file.ExpiresAt == nil {
+			expiresAt := time.Now().Add(rule.Expiry)
+			file.ExpiresAt = &expiresAt
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+//ApplyRulesToNamespace applies the rules of a namespace to all of its existing files
+func ApplyRulesToNamespace(db *gorm.DB, job *Job, namespace *Namespace, user *User) error {
+	rules, err := FindRules(db, namespace)
+	if err != nil {
+		job.Fail(db, err)
+		return err
+	}
+
+	var files []File
+	err = db.Model(&File{}).Where("namespace_id = ?", namespace.ID).Preload("Tags").Preload("Groups").Find(&files).Error
+	if err != nil {
+		job.Fail(db, err)
+		return err
+	}
+
+	if err = job.Start(db, int64(len(files))); err != nil {
+		return err
+	}
+
+	var changed int
+	for i := range files {
+		files[i].Namespace = namespace
+
+		if files[i].ApplyRules(db, rules, user) {
+			if err = files[i].Save(db); err != nil {
+				job.Fail(db, err)
+				return err
+			}
+			changed++
+		}
+
+		job.SetProgress(db, int64(i+1))
+	}
+
+	return job.Done(db, fmt.Sprintf("updated %d of %d files", changed, len(files)))
+}
@@ -1,9 +1,14 @@
 package models
 
 import (
+	"strings"
+
 	"github.com/jinzhu/gorm"
 )
 
+//TagSeparator separates the levels of a nested tag
+const TagSeparator = "/"
+
 //Tag a filetag
 type Tag struct {
 	gorm.Model
@@ -36,7 +41,7 @@ func TagsFromStringArr(arr []string, namespace Namespace, user *User) []Tag {
 	var tags []Tag
 	for _, tag := range arr {
 		tags = append(tags, Tag{
-			Name:      tag,
+			Name:      NormalizeTagName(tag),
 			User:      user,
 			UserID:    user.ID,
 			Namespace: &namespace,
@@ -45,10 +50,11 @@ func TagsFromStringArr(arr []string, namespace Namespace, user *User) []Tag {
 	return tags
 }
 
-//FindTags find namespace in DB
+//FindTags find tags and their children in DB
 func FindTags(db *gorm.DB, sTags []string, namespace *Namespace) []Tag {
 	var tags []Tag
-	db.Model(&Tag{}).Where("name in (?) AND namespace_id = ?", sTags, namespace.ID).Find(&tags)
+	condition, args := tagCondition("name", sTags)
+	db.Model(&Tag{}).Where(condition+" AND namespace_id = ?", append(args, namespace.ID)...).Find(&tags)
 	return tags
 }
 
@@ -65,7 +71,7 @@ func TagArrToStringArr(tags []Tag) []string {
 func GetTag(db *gorm.DB, name string, namespace *Namespace, user *User) *Tag {
 	var tag Tag
 	db.Where(&Tag{
-		Name:        name,
+		Name:        NormalizeTagName(name),
 		NamespaceID: namespace.ID,
 		UserID:      user.ID,
 	}).FirstOrCreate(&tag)
@@ -89,3 +95,36 @@ func FindTag(db *gorm.DB, name string, namespace *Namespace, user *User) (*Tag,
 
 	return &tag, nil
 }
+
+//NormalizeTagName removes empty levels of a nested tag
+func NormalizeTagName(name string) string {
+	if !strings.Contains(name, TagSeparator) {
+		return name
+	}
+
+	var parts []string
+	for _, part := range strings.Split(name, TagSeparator) {
+		if part = strings.TrimSpace(part); len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, TagSeparator)
+}
+
+//IsTagOrChild return true if tag is parent or one of its children
+func IsTagOrChild(tag, parent string) bool {
+	return tag == parent || strings.HasPrefix(tag, parent+TagSeparator)
+}
+
+//Build a condition matching the tags and all of their children
+func tagCondition(column string, tags []string) (string, []interface{}) {
+	condition := column + " IN (?)"
+	args := []interface{}{tags}
+	for i := range tags {
+		condition += " OR " + column + " LIKE ?"
+		args = append(args, escapeLike(tags[i])+TagSeparator+"%")
+	}
+
+	return "(" + condition + ")", args
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+//FindTagTreeUsage returns the usage of all tags and their parents. Files and size of a
+//parent include all files of its children. Namespaces can be accessed using 'namespaces'
+func FindTagTreeUsage(db *gorm.DB, prefix string, filter func(*gorm.DB) *gorm.DB) ([]AttributeUsage, error) {
+	// Expand each tag into all of its parent paths
+	paths := db.Table("tags").
+		Select(`DISTINCT array_to_string((string_to_array(tags.name, '` + TagSeparator + `'))[1:level], '` + TagSeparator + `') AS path,
+			namespaces.name AS namespace, files_tags.file_id AS file_id`).
+		Joins("INNER JOIN namespaces ON namespaces.id = tags.namespace_id AND namespaces.deleted_at IS NULL").
+		Joins("CROSS JOIN LATERAL generate_series(1, array_length(string_to_array(tags.name, '" + TagSeparator + "'), 1)) AS level").
+		Joins("LEFT JOIN files_tags ON files_tags.tag_id = tags.id").
+		Where("tags.deleted_at IS NULL")
+
+	if len(prefix) > 0 {
+		paths = paths.Where("tags.name LIKE ?", escapeLike(prefix)+"%")
+	}
+
+	paths = filter(paths)
+
+	var usage []AttributeUsage
+	err := db.Raw(`SELECT paths.path AS name, paths.namespace AS namespace,
+			COUNT(files.id) AS files, COALESCE(SUM(files.file_size), 0) AS size, MAX(files.created_at) AS last_used
+		FROM ? AS paths
+		LEFT JOIN files ON files.id = paths.file_id AND files.deleted_at IS NULL
+		GROUP BY paths.path, paths.namespace
+		ORDER BY paths.namespace, paths.path`, paths.SubQuery()).
+		Scan(&usage).Error
+
+	return usage, err
+}
+
+//BuildTagTree builds a tree of nested tags
+func BuildTagTree(usage []AttributeUsage) []TagTreeItem {
+	type node struct {
+		item     TagTreeItem
+		children []*node
+	}
+
+	var roots []*node
+	nodes := make(map[[2]string]*node)
+
+	// Sort parents before their children
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].Namespace != usage[j].Namespace {
+			return usage[i].Namespace < usage[j].Namespace
+		}
+		return strings.Count(usage[i].Name, TagSeparator) < strings.Count(usage[j].Name, TagSeparator)
+	})
+
+	for _, item := range usage {
+		n := &node{
+			item: TagTreeItem{
+				Name:      item.Name[strings.LastIndex(item.Name, TagSeparator)+1:],
+				Path:      item.Name,
+				Namespace: item.Namespace,
+				Files:     item.Files,
+				Size:      item.Size,
+				LastUsed:  item.LastUsed,
+			},
+		}
+		nodes[[2]string{item.Namespace, item.Name}] = n
+
+		// Append to parent
+		if i := strings.LastIndex(item.Name, TagSeparator); i > 0 {
+			if parent, ok := nodes[[2]string{item.Namespace, item.Name[:i]}]; ok {
+				parent.children = append(parent.children, n)
+				continue
+			}
+		}
+
+		roots = append(roots, n)
+	}
+
+	var build func([]*node) []TagTreeItem
+	build = func(list []*node) []TagTreeItem {
+		items := make([]TagTreeItem, len(list))
+		for i, n := range list {
+			items[i] = n.item
+			if len(n.children) > 0 {
+				items[i].Children = build(n.children)
+			}
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Namespace != items[j].Namespace {
+				return items[i].Namespace < items[j].Namespace
+			}
+			return items[i].Name < items[j].Name
+		})
+
+		return items
+	}
+
+	return build(roots)
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"os"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+//DefaultThumbnailSize size used if no size was requested
+const DefaultThumbnailSize = "small"
+
+//ThumbnailSizes max width/height of the thumbnail sizes
+var ThumbnailSizes = map[string]int{
+	"small":  256,
+	"medium": 800,
+	"large":  1600,
+}
+
+//ThumbnailMimes images thumbnails can be generated for
+var ThumbnailMimes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+}
+
+//IsValidThumbnailSize return true if size is a thumbnail size
+func IsValidThumbnailSize(size string) bool {
+	_, ok := ThumbnailSizes[size]
+	return ok
+}
+
+//GetThumbnailFile return path of a thumbnail of a local file
+func (config Config) GetThumbnailFile(localName, size string) string {
+	return config.GetStorageFile(localName + ".thumb_" + size)
+}
+
+//SetHasThumbnail sets whether thumbnails of the file exist
+func (file *File) SetHasThumbnail(db *gorm.DB, hasThumbnail bool) error {
+	file.HasThumbnail = hasThumbnail
+	return db.Model(file).UpdateColumn("has_thumbnail", hasThumbnail).Error
+}
+
+//RemoveThumbnails removes all thumbnails of a local file
+func RemoveThumbnails(config *Config, localName string) {
+	for size := range ThumbnailSizes {
+		err := os.Remove(config.GetThumbnailFile(localName, size))
+		if err != nil && !os.IsNotExist(err) {
+			log.Warn(err)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func setHeadersFromStr(headers string, header *http.Header) {
@@ -17,3 +19,55 @@ func setHeadersFromStr(headers string, header *http.Header) {
 		(*header).Set(key, kp[1])
 	}
 }
+
+//Split a comma separated list
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+//Join a list to a comma separated string
+func joinList(items []string) string {
+	var list []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+//Return true if mime matches the pattern. Patterns can end with a *
+func matchMime(pattern, mime string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(mime, pattern[:len(pattern)-1])
+	}
+
+	return pattern == mime
+}
+
+//ParseExpiry parses an expiry like "30d" or "12h". Empty string means no expiry
+func ParseExpiry(expiry string) (time.Duration, error) {
+	expiry = strings.TrimSpace(expiry)
+	if len(expiry) == 0 {
+		return 0, nil
+	}
+
+	// time.ParseDuration doesn't know days
+	if strings.HasSuffix(expiry, "d") {
+		days, err := strconv.ParseUint(expiry[:len(expiry)-1], 10, 32)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(expiry)
+}
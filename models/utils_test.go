@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
+)
+
+//SQLite driver providing functions of postgres used by queries
+const testDriver = "sqlite3_test"
+
+func init() {
+	sql.Register(testDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("now", func() string {
+				return time.Now().UTC().Format("2006-01-02 15:04:05")
+			}, false)
+		},
+	})
+}
+
+//Open a migrated in-memory database
+func newTestDB(t *testing.T) *gorm.DB {
+	conn, err := sql.Open(testDriver, "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open("sqlite3", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	err = db.AutoMigrate(
+		&Role{},
+		&Namespace{},
+		&Tag{},
+		&File{},
+		&Group{},
+		&User{},
+		&Job{},
+		&FileContent{},
+		&FileMeta{},
+		&Rule{},
+		&Collection{},
+	).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+//Create a config using temporary filestore and quarantine directories
+func newTestConfig(t *testing.T) *Config {
+	dir, err := ioutil.TempDir("", "models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	config := &Config{}
+	config.Server.PathConfig.FileStore = path.Join(dir, "files")
+	config.Server.PathConfig.Quarantine = path.Join(dir, "quarantine")
+	for _, dir := range []string{config.Server.PathConfig.FileStore, config.Server.PathConfig.Quarantine} {
+		if err = os.Mkdir(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return config
+}
+
+//Create a user and a namespace owned by the user
+func newTestNamespace(t *testing.T, db *gorm.DB, name string) (*User, *Namespace) {
+	user := &User{Username: "user"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	namespace := &Namespace{Name: name, User: user, UserID: user.ID}
+	if err := db.Create(namespace).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return user, namespace
+}
+
+//Return true if file exists
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
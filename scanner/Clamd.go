@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+)
+
+//Size of the chunks sent to clamd. Has to be less than its StreamMaxLength
+const clamdChunkSize = 64 * 1024
+
+//Clamd scans content using the INSTREAM command of clamd
+type Clamd struct {
+	Network string
+	Address string
+}
+
+//NewClamd creates a clamd scanner. address is either
+//tcp://host:port, unix:///path/to/socket, host:port or /path/to/socket
+func NewClamd(address string) (*Clamd, error) {
+	switch {
+	case len(address) == 0:
+		return nil, errors.New("clamd address missing")
+	case strings.HasPrefix(address, "tcp://"):
+		return &Clamd{Network: "tcp", Address: strings.TrimPrefix(address, "tcp://")}, nil
+	case strings.HasPrefix(address, "unix://"):
+		return &Clamd{Network: "unix", Address: strings.TrimPrefix(address, "unix://")}, nil
+	case strings.HasPrefix(address, "/"):
+		return &Clamd{Network: "unix", Address: address}, nil
+	}
+
+	return &Clamd{Network: "tcp", Address: address}, nil
+}
+
+//Scan sends content to clamd
+func (clamd Clamd) Scan(ctx context.Context, content io.Reader) (*Result, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, clamd.Network, clamd.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Close the connection if ctx gets canceled
+	done := make(chan struct{})
+	defer close(done)
+	go (func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	})()
+
+	writeErr := clamd.stream(conn, content)
+
+	// clamd replies before closing the connection if the stream is too large
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && len(reply) == 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		return nil, err
+	}
+
+	return parseClamdReply(reply)
+}
+
+//Write the INSTREAM command followed by the content
+func (clamd Clamd) stream(conn net.Conn, content io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := content.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// A chunk of length zero ends the stream
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+//Parse a reply like "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (*Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &Result{Infected: true, Signature: signature}, nil
+	case reply == "stream: OK":
+		return &Result{}, nil
+	}
+
+	return nil, errors.New("clamd: " + reply)
+}
@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/JojiiOfficial/DataManagerServer/models"
+)
+
+//Start a fake clamd. reply returns the answer to the streamed content
+func startFakeClamd(t *testing.T, reply func(content []byte) string) (string, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	received := make(chan []byte, 1)
+	go (func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			content, err := readInstream(conn)
+			if err != nil {
+				conn.Write([]byte("INSTREAM: " + err.Error() + ". ERROR\x00"))
+			} else {
+				received <- content
+				conn.Write([]byte(reply(content) + "\x00"))
+			}
+			conn.Close()
+		}
+	})()
+
+	return listener.Addr().String(), received
+}
+
+//Read the zINSTREAM command and its chunks
+func readInstream(conn net.Conn) ([]byte, error) {
+	reader := bufio.NewReader(conn)
+
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return nil, err
+	}
+	if command != "zINSTREAM\x00" {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+		if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//Create a config using clamd at address and a stored file with content
+func setupScan(t *testing.T, address string, content []byte) (*models.Config, *models.File) {
+	dir, err := ioutil.TempDir("", "scanner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	config := &models.Config{}
+	config.Server.PathConfig.FileStore = dir
+	config.Server.Scanner.Type = ClamdScanner
+	config.Server.Scanner.Address = "tcp://" + address
+	config.Server.Scanner.Timeout = 10
+
+	file := &models.File{LocalName: "upload"}
+	if err = ioutil.WriteFile(config.GetStorageFile(file.LocalName), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return config, file
+}
+
+func TestClamdScanFile(t *testing.T) {
+	address, received := startFakeClamd(t, func(content []byte) string {
+		switch {
+		case bytes.Contains(content, []byte("EICAR")):
+			return "stream: Eicar-Test-Signature FOUND"
+		case bytes.Contains(content, []byte("broken")):
+			return "stream: Can't allocate memory ERROR"
+		}
+		return "stream: OK"
+	})
+
+	tests := []struct {
+		name      string
+		content   []byte
+		infected  bool
+		signature string
+		err       string
+	}{
+		{name: "clean", content: []byte("hello world")},
+		{name: "multiple chunks", content: bytes.Repeat([]byte("a"), 3*clamdChunkSize+10)},
+		{name: "empty", content: []byte{}},
+		{name: "infected", content: []byte("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR"), infected: true, signature: "Eicar-Test-Signature"},
+		{name: "error", content: []byte("broken"), err: "clamd: stream: Can't allocate memory ERROR"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, file := setupScan(t, address, test.content)
+
+			result, err := ScanFile(config, file)
+			if len(test.err) > 0 {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if result.Infected != test.infected || result.Signature != test.signature {
+				t.Fatalf("expected infected=%v signature=%q, got %+v", test.infected, test.signature, result)
+			}
+
+			if content := <-received; !bytes.Equal(content, test.content) {
+				t.Fatalf("clamd received %d bytes, expected %d", len(content), len(test.content))
+			}
+		})
+	}
+}
+
+func TestClamdScanFileUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	config, file := setupScan(t, address, []byte("hello world"))
+	if result, err := ScanFile(config, file); err == nil {
+		t.Fatalf("expected error, got %+v", result)
+	}
+}
+
+func TestNewClamd(t *testing.T) {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+	}{
+		{"tcp://localhost:3310", "tcp", "localhost:3310"},
+		{"localhost:3310", "tcp", "localhost:3310"},
+		{"unix:///run/clamd.sock", "unix", "/run/clamd.sock"},
+		{"/run/clamd.sock", "unix", "/run/clamd.sock"},
+	}
+
+	for _, test := range tests {
+		clamd, err := NewClamd(test.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clamd.Network != test.network || clamd.Address != test.addr {
+			t.Errorf("%s: got %s %s", test.address, clamd.Network, clamd.Address)
+		}
+	}
+
+	if _, err := NewClamd(""); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected missing address error, got %v", err)
+	}
+}
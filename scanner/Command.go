@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+//Exit code of a command if the content is infected. Used by clamscan and clamdscan
+const infectedExitCode = 1
+
+//Command scans content using an external command. The content is passed to
+//stdin. Exit code 0 means clean, 1 infected. The output is used as signature
+type Command struct {
+	Args []string
+}
+
+//NewCommand creates a command scanner. args[0] is the command, eg. clamdscan -
+func NewCommand(args []string) (*Command, error) {
+	if len(args) == 0 || len(args[0]) == 0 {
+		return nil, errors.New("scanner command missing")
+	}
+
+	return &Command{Args: args}, nil
+}
+
+//Scan runs the command with content as stdin
+func (command Command) Scan(ctx context.Context, content io.Reader) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command.Args[0], command.Args[1:]...)
+	cmd.Stdin = content
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return &Result{}, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == infectedExitCode {
+		return &Result{
+			Infected:  true,
+			Signature: parseSignature(stdout.String()),
+		}, nil
+	}
+
+	if msg := strings.TrimSpace(stderr.String()); len(msg) > 0 {
+		return nil, errors.New(command.Args[0] + ": " + msg)
+	}
+
+	return nil, err
+}
+
+//Get the signature from the output. Lines like "stdin: Eicar-Signature FOUND" are reduced to the signature
+func parseSignature(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, " FOUND") {
+			line = strings.TrimSuffix(line, " FOUND")
+			if i := strings.LastIndex(line, ": "); i >= 0 {
+				line = line[i+2:]
+			}
+			return line
+		}
+	}
+
+	// Use first line of unknown outputs
+	line := strings.TrimSpace(strings.SplitN(output, "\n", 2)[0])
+	if len(line) == 0 {
+		return "unknown"
+	}
+	return line
+}
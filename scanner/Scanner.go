@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+)
+
+//Scanner types
+const (
+	ClamdScanner   = "clamd"
+	CommandScanner = "command"
+)
+
+//ErrUnknownScanner the configured scanner type doesn't exist
+var ErrUnknownScanner = errors.New("unknown scanner type")
+
+//Scanner scans content for malware
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (*Result, error)
+}
+
+//Result result of a scan
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+//New creates the scanner set in the config. Returns nil if scanning is disabled
+func New(config *models.Config) (Scanner, error) {
+	conf := config.Server.Scanner
+
+	switch conf.Type {
+	case "":
+		return nil, nil
+	case ClamdScanner:
+		return NewClamd(conf.Address)
+	case CommandScanner:
+		return NewCommand(conf.Command)
+	}
+
+	return nil, ErrUnknownScanner
+}
+
+//IsEnabled return true if uploads are scanned
+func IsEnabled(config *models.Config) bool {
+	return len(config.Server.Scanner.Type) > 0
+}
+
+//ScanFile scans the stored content of a file. Returns nil if scanning is disabled
+func ScanFile(config *models.Config, file *models.File) (*Result, error) {
+	scanner, err := New(config)
+	if err != nil || scanner == nil {
+		return nil, err
+	}
+
+	blob, err := filestore.Open(config, file)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Server.Scanner.Timeout)*time.Second)
+	defer cancel()
+
+	return scanner.Scan(ctx, blob)
+}
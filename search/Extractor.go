@@ -0,0 +1,181 @@
+package search
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//ErrNotExtractable error if no text can be extracted from a file
+var ErrNotExtractable = errors.New("can't extract text from file")
+
+//Mime types containing plain text
+var textMimes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"application/x-javascript",
+	"application/x-sh",
+	"application/x-php",
+	"application/x-python",
+	"application/x-ruby",
+	"application/x-perl",
+	"application/x-tcl",
+	"application/x-yaml",
+	"application/toml",
+	"application/sql",
+	"image/svg+xml",
+}
+
+//Extensions of source code and other text files which may not be detected as text
+var textExtensions = []string{
+	".c", ".h", ".cpp", ".hpp", ".cc", ".cs", ".go", ".rs", ".java", ".kt", ".scala",
+	".js", ".ts", ".jsx", ".tsx", ".py", ".rb", ".php", ".pl", ".lua", ".swift",
+	".sh", ".bash", ".zsh", ".ps1", ".sql", ".json", ".yml", ".yaml", ".toml",
+	".ini", ".cfg", ".conf", ".md", ".rst", ".txt", ".csv", ".tsv", ".log", ".xml", ".html", ".css",
+}
+
+//XML parts containing the text of office documents
+var officeParts = map[string][]string{
+	// Office Open XML
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   {"word/document.xml"},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         {"xl/sharedStrings.xml"},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": {"ppt/slides/slide*.xml"},
+	// OpenDocument
+	"application/vnd.oasis.opendocument.text":         {"content.xml"},
+	"application/vnd.oasis.opendocument.spreadsheet":  {"content.xml"},
+	"application/vnd.oasis.opendocument.presentation": {"content.xml"},
+}
+
+//IsExtractable return true if text can be extracted from a file with the given mime and name
+func IsExtractable(mime, name string) bool {
+	return isTextFile(mime, name) || len(officeParts[mime]) > 0
+}
+
+//ExtractText extracts at most maxSize bytes of text from content of the given size
+func ExtractText(content io.ReaderAt, size int64, mime, name string, maxSize int64) (string, error) {
+	if isTextFile(mime, name) {
+		return extractPlainText(io.NewSectionReader(content, 0, size), maxSize)
+	}
+
+	if parts, ok := officeParts[mime]; ok {
+		return extractOfficeText(content, size, parts, maxSize)
+	}
+
+	return "", ErrNotExtractable
+}
+
+func isTextFile(mime, name string) bool {
+	for _, textMime := range textMimes {
+		if strings.HasPrefix(mime, textMime) {
+			return true
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, textExt := range textExtensions {
+		if ext == textExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extractPlainText(r io.Reader, maxSize int64) (string, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, maxSize))
+	if err != nil {
+		return "", err
+	}
+
+	return cleanText(b), nil
+}
+
+//Office documents are zip archives containing xml files
+func extractOfficeText(content io.ReaderAt, size int64, parts []string, maxSize int64) (string, error) {
+	zr, err := zip.NewReader(content, size)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep order of slides
+	files := zr.File
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	var text strings.Builder
+	for _, file := range files {
+		if !matchesPart(file.Name, parts) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+
+		err = extractXMLText(rc, &text, maxSize)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+
+		if int64(text.Len()) >= maxSize {
+			break
+		}
+	}
+
+	return cleanText([]byte(text.String())), nil
+}
+
+func matchesPart(name string, parts []string) bool {
+	for _, part := range parts {
+		if ok, _ := filepath.Match(part, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//Write the chardata of all xml elements into text
+func extractXMLText(r io.Reader, text *strings.Builder, maxSize int64) error {
+	decoder := xml.NewDecoder(r)
+	for int64(text.Len()) < maxSize {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			// Separate paragraphs and cells
+			if t.Name.Local == "p" || t.Name.Local == "t" || t.Name.Local == "si" {
+				text.WriteByte(' ')
+			}
+		}
+	}
+
+	return nil
+}
+
+//Return valid utf8 without null bytes, which postgres can't store
+func cleanText(b []byte) string {
+	s := string(b)
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "")
+	}
+	return strings.ReplaceAll(s, "\x00", "")
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+//IndexFile extracts the text of a file and stores it in the search index.
+//Encrypted files and files without extractable text are removed from the index
+func IndexFile(db *gorm.DB, config *models.Config, file models.File) error {
+	if !config.Server.Search.Enabled {
+		return nil
+	}
+
+	// The server can't read encrypted files
+	if file.Encryption.Valid || !IsExtractable(file.FileType, file.Name) {
+		return models.DeleteFileContent(db, file.ID)
+	}
+
+	blob, err := filestore.Open(config, &file)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	text, err := ExtractText(blob, blob.Size(), file.FileType, file.Name, config.Server.Search.MaxIndexSize)
+	if err != nil {
+		log.Debugf("Can't extract text of %d: %s", file.ID, err)
+		return models.DeleteFileContent(db, file.ID)
+	}
+
+	return models.SetFileContent(db, config, file.ID, text)
+}
@@ -29,6 +29,11 @@ func ConnectToDatabase(config *models.Config) (*gorm.DB, error) {
 		&models.Group{},
 		&models.User{},
 		&models.LoginSession{},
+		&models.Job{},
+		&models.FileContent{},
+		&models.FileMeta{},
+		&models.Rule{},
+		&models.Collection{},
 	).Error
 
 	//Return error if automigration fails
@@ -36,6 +41,21 @@ func ConnectToDatabase(config *models.Config) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	//Store ciphers by their name
+	if err = models.MigrateEncryption(db); err != nil {
+		return nil, err
+	}
+
+	//Merge duplicate tags and groups and keep them unique
+	if err = models.CreateAttributeIndexes(db); err != nil {
+		return nil, err
+	}
+
+	//Create full-text search index
+	if err = models.CreateSearchIndex(db); err != nil {
+		return nil, err
+	}
+
 	createRoles(db, config)
 
 	//Create default namespace
@@ -0,0 +1,146 @@
+package thumbnail
+
+import (
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"sort"
+
+	"github.com/JojiiOfficial/DataManagerServer/filestore"
+	"github.com/JojiiOfficial/DataManagerServer/models"
+	"github.com/JojiiOfficial/gaw"
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/image/draw"
+
+	// Register decoders
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+)
+
+//ErrImageTooLarge error if an image has too many pixels to be decoded
+var ErrImageTooLarge = errors.New("image too large")
+
+//jpegQuality quality of generated jpeg thumbnails
+const jpegQuality = 85
+
+//IsSupported return true if thumbnails can be generated for the file
+func IsSupported(file models.File) bool {
+	return !file.Encryption.Valid && gaw.IsInStringArray(file.FileType, models.ThumbnailMimes)
+}
+
+//Generate creates all thumbnail sizes of an image file
+func Generate(db *gorm.DB, config *models.Config, file models.File) error {
+	if !config.Server.Thumbnails.Enabled || !IsSupported(file) {
+		return nil
+	}
+
+	img, err := decode(config, &file, config.Server.Thumbnails.MaxPixels)
+	if err != nil {
+		log.Debugf("Can't create thumbnail of %d: %s", file.ID, err)
+		return nil
+	}
+
+	// Scale the largest size first and use it as source for smaller ones
+	var sizes []string
+	for size := range models.ThumbnailSizes {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return models.ThumbnailSizes[sizes[i]] > models.ThumbnailSizes[sizes[j]]
+	})
+
+	for _, size := range sizes {
+		img = scale(img, models.ThumbnailSizes[size])
+
+		if err = write(config, &file, config.GetThumbnailFile(file.LocalName, size), img); err != nil {
+			models.RemoveThumbnails(config, file.LocalName)
+			return err
+		}
+	}
+
+	return file.SetHasThumbnail(db, true)
+}
+
+//Decode an image if it's not larger than maxPixels
+func decode(config *models.Config, file *models.File, maxPixels int64) (image.Image, error) {
+	f, err := filestore.Open(config, file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Check size before allocating the image
+	imgConfig, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	if maxPixels > 0 && int64(imgConfig.Width)*int64(imgConfig.Height) > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	if _, err = f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+//Scale an image to fit into maxSize. Smaller images are kept
+func scale(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	if width > height {
+		height = height * maxSize / width
+		width = maxSize
+	} else {
+		width = width * maxSize / height
+		height = maxSize
+	}
+
+	// Keep at least one pixel of very narrow images
+	if width == 0 {
+		width = 1
+	}
+	if height == 0 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+//Write an image as jpeg or as png if it has transparent pixels.
+//Thumbnails of files encrypted at rest are encrypted too
+func write(config *models.Config, file *models.File, path string, img image.Image) error {
+	tmpFile := path + ".tmp"
+	f, err := filestore.CreatePath(config, file, tmpFile)
+	if err != nil {
+		return err
+	}
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(f, img)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	return os.Rename(tmpFile, path)
+}